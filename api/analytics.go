@@ -0,0 +1,282 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// AnalyticsMinLimit and AnalyticsMaxLimit are the smallest and largest number of rows in a page of an Analytics report.
+	// The limit must be a multiple of AnalyticsMinLimit.
+	AnalyticsMinLimit = 25
+	AnalyticsMaxLimit = 1000
+
+	// analyticsMaxEmptyPages is the number of pages without rows Alma can return
+	// while the report is still running before the report is abandoned.
+	analyticsMaxEmptyPages = 30
+
+	// analyticsEmptyPageWait is the time to wait after a page without rows before asking for the next page.
+	analyticsEmptyPageWait = 2 * time.Second
+
+	// sawSQLNamespace is the namespace of the attributes Analytics adds to the rowset schema.
+	sawSQLNamespace = "urn:saw-sql"
+)
+
+// The kinds of values in Analytics columns.
+const (
+	KindString   = "string"
+	KindInteger  = "integer"
+	KindDecimal  = "decimal"
+	KindBoolean  = "boolean"
+	KindDate     = "date"
+	KindDateTime = "dateTime"
+)
+
+// AnalyticsQuery stores the parameters used to run an Analytics report.
+type AnalyticsQuery struct {
+	// Path is the path of the report in the catalog, like /shared/Carleton University/Reports/Items.
+	Path string
+	// Filter is an optional sawx filter expression, in XML.
+	Filter string
+	// Limit is the number of rows in each page, from AnalyticsMinLimit to AnalyticsMaxLimit.
+	Limit int
+}
+
+// AnalyticsColumn describes a column of an Analytics report, read from the rowset schema.
+type AnalyticsColumn struct {
+	// Name is the name of the column's element in each row, like Column1.
+	Name string
+	// Heading is the heading of the column in the report.
+	Heading string
+	// Type is the XML schema type of the column, like int or string, without the xsd prefix.
+	Type string
+	// SQLType is the Analytics SQL type of the column, like varchar or timestamp.
+	SQLType string
+}
+
+// Placeholder returns true if the column is the placeholder column Analytics adds to the start of every report.
+func (c AnalyticsColumn) Placeholder() bool {
+	return c.Name == "Column0" && c.Heading == "0"
+}
+
+// Kind returns the kind of value in the column, like KindInteger or KindDate.
+func (c AnalyticsColumn) Kind() string {
+	switch c.Type {
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger", "positiveInteger", "unsignedInt", "unsignedLong":
+		return KindInteger
+	case "decimal", "double", "float":
+		return KindDecimal
+	case "boolean":
+		return KindBoolean
+	case "date":
+		return KindDate
+	case "dateTime":
+		return KindDateTime
+	}
+	return KindString
+}
+
+// Value decodes the text of the column in a row as an int64, float64, bool, time.Time, or string, depending on its Kind.
+func (c AnalyticsColumn) Value(text string) (value interface{}, err error) {
+	switch c.Kind() {
+	case KindInteger:
+		value, err = strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case KindDecimal:
+		value, err = strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case KindBoolean:
+		value, err = strconv.ParseBool(strings.TrimSpace(text))
+	case KindDate:
+		// Dates can have a time zone, like 2020-01-02Z.
+		value, err = time.Parse("2006-01-02", strings.TrimSuffix(strings.TrimSpace(text), "Z"))
+	case KindDateTime:
+		value, err = time.Parse("2006-01-02T15:04:05", strings.TrimSuffix(strings.TrimSpace(text), "Z"))
+	default:
+		return text, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the %v value '%v' in column '%v' is not valid: %w", c.Type, text, c.Heading, err)
+	}
+	return value, nil
+}
+
+// AnalyticsRow is a row of an Analytics report, mapping column names to their text. Null values are missing.
+type AnalyticsRow map[string]string
+
+// Values decodes the row into the values of the columns, in order. Null values are nil.
+func (r AnalyticsRow) Values(columns []AnalyticsColumn) (values []interface{}, err error) {
+	values = make([]interface{}, len(columns))
+	for i, column := range columns {
+		text, found := r[column.Name]
+		if !found {
+			continue
+		}
+		values[i], err = column.Value(text)
+		if err != nil {
+			return values, err
+		}
+	}
+	return values, nil
+}
+
+// analyticsPage stores a page of an Analytics report as returned from the API.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_report.xsd/
+type analyticsPage struct {
+	XMLName         xml.Name `xml:"report"` //the XML root element must have the name "report" or else Unmarshal returns an error.
+	ResumptionToken string   `xml:"QueryResult>ResumptionToken"`
+	IsFinished      bool     `xml:"QueryResult>IsFinished"`
+	Rowset          struct {
+		// The schema is only included in the first page.
+		Elements []struct {
+			// The schema and saw-sql namespaces both have a type attribute, so the attributes are read by hand.
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"schema>complexType>sequence>element"`
+		Rows []struct {
+			Columns []struct {
+				XMLName xml.Name
+				Text    string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"Row"`
+	} `xml:"QueryResult>ResultXml>rowset"`
+}
+
+// columns returns the columns described by the page's schema.
+func (p analyticsPage) columns() (columns []AnalyticsColumn) {
+	for _, element := range p.Rowset.Elements {
+		column := AnalyticsColumn{}
+		for _, attr := range element.Attrs {
+			switch {
+			case attr.Name.Space == sawSQLNamespace && attr.Name.Local == "columnHeading":
+				column.Heading = attr.Value
+			case attr.Name.Space == sawSQLNamespace && attr.Name.Local == "type":
+				column.SQLType = attr.Value
+			case attr.Name.Space == "" && attr.Name.Local == "name":
+				column.Name = attr.Value
+			case attr.Name.Space == "" && attr.Name.Local == "type":
+				// Remove the namespace prefix, like xsd:int.
+				column.Type = attr.Value[strings.LastIndex(attr.Value, ":")+1:]
+			}
+		}
+		if column.Heading == "" {
+			column.Heading = column.Name
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// rows returns the rows of the page.
+func (p analyticsPage) rows() (rows []AnalyticsRow) {
+	for _, r := range p.Rowset.Rows {
+		row := AnalyticsRow{}
+		for _, column := range r.Columns {
+			row[column.XMLName.Local] = column.Text
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// AnalyticsReportPages runs the Analytics report, and calls page with the columns and the rows of each page of results.
+// The page function is called for the first page even if it has no rows, so the columns are always known.
+// Pages are requested one at a time, with the resumption token returned with the first page, until the report is finished.
+// If page returns an error, no more pages are requested and the error is returned.
+func (c Client) AnalyticsReportPages(ctx context.Context, query AnalyticsQuery, page func(columns []AnalyticsColumn, rows []AnalyticsRow) error) error {
+	limit := query.Limit
+	if limit == 0 {
+		limit = AnalyticsMaxLimit
+	}
+	if limit < AnalyticsMinLimit || limit > AnalyticsMaxLimit || limit%AnalyticsMinLimit != 0 {
+		return fmt.Errorf("the limit must be a multiple of %v from %v to %v", AnalyticsMinLimit, AnalyticsMinLimit, AnalyticsMaxLimit)
+	}
+	q := url.Values{}
+	q.Set("path", query.Path)
+	if query.Filter != "" {
+		q.Set("filter", query.Filter)
+	}
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("col_names", "true")
+	token := ""
+	columns := []AnalyticsColumn{}
+	for first, empty := true, 0; ; first = false {
+		p, err := c.analyticsPage(ctx, q)
+		if err != nil {
+			return err
+		}
+		if first {
+			token = p.ResumptionToken
+			columns = p.columns()
+			if len(columns) == 0 {
+				return fmt.Errorf("the first page of the report '%v' has no columns", query.Path)
+			}
+			// Later pages are requested with only the token and the limit.
+			q = url.Values{}
+			q.Set("token", token)
+			q.Set("limit", strconv.Itoa(limit))
+		}
+		rows := p.rows()
+		if first || len(rows) != 0 {
+			empty = 0
+			err = page(columns, rows)
+			if err != nil {
+				return err
+			}
+		}
+		if p.IsFinished {
+			return nil
+		}
+		if token == "" {
+			return fmt.Errorf("the report '%v' is not finished, but no resumption token was returned", query.Path)
+		}
+		if len(rows) == 0 {
+			// The report is still running in Analytics.
+			empty++
+			if empty >= analyticsMaxEmptyPages {
+				return fmt.Errorf("the report '%v' returned %v pages with no rows without finishing", query.Path, empty)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(analyticsEmptyPageWait):
+			}
+		}
+	}
+}
+
+// AnalyticsReport runs the Analytics report, and returns all the rows.
+func (c Client) AnalyticsReport(ctx context.Context, query AnalyticsQuery) (columns []AnalyticsColumn, rows []AnalyticsRow, err error) {
+	err = c.AnalyticsReportPages(ctx, query, func(pageColumns []AnalyticsColumn, pageRows []AnalyticsRow) error {
+		columns = pageColumns
+		rows = append(rows, pageRows...)
+		return nil
+	})
+	return columns, rows, err
+}
+
+// analyticsPage returns a page of an Analytics report.
+func (c Client) analyticsPage(ctx context.Context, q url.Values) (page analyticsPage, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/analytics/reports?"+q.Encode(), nil)
+	if err != nil {
+		return page, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return page, err
+	}
+	err = xml.Unmarshal(body, &page)
+	if err != nil {
+		return page, fmt.Errorf("unmarshalling report XML failed: %w\n%v", err, string(body))
+	}
+	return page, nil
+}
@@ -15,9 +15,11 @@ import (
 	"net/http"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/cu-library/almatoolkit/shared"
 	"github.com/schollz/progressbar/v3"
 	"golang.org/x/time/rate"
 )
@@ -29,7 +31,7 @@ const (
 	// LimitParam is the limit parameter to offset+limit calls.
 	LimitParam = 100
 
-	// LimiterRate is the maximum number of API calls per second the tool will allow.
+	// LimiterRate is the default maximum number of API calls per second the tool will allow.
 	// Per https://developers.exlibrisgroup.com/alma/apis/#threshold, the real limit is 25,
 	// but we want to allow other API calls to succeeed while the tool is running.
 	LimiterRate = 15
@@ -45,9 +47,10 @@ const (
 type Client struct {
 	// Client is the embedded http client.
 	*http.Client
-	// limiter is a rate limiter which ensures the client does not go over 15 API calls per second.
-	//
-	limiter *rate.Limiter
+	// limiter is a rate limiter which ensures the client does not go over its API calls per second.
+	limiter Limiter
+	// shared is set when the rate limit and the remaining calls are shared with other processes.
+	shared *shared.Limiter
 	// Host is the host name (domain name) for the Alma API we are calling.
 	Host string
 	// Key is the authorization key to use when calling the Alma API.
@@ -55,19 +58,38 @@ type Client struct {
 	// Threshold is the minimum number of API calls remaining before
 	// the Cancel function is called.
 	Threshold int
+	// Workers is the number of concurrent workers used to make API calls.
+	// If it is zero or less, the number of CPUs is used.
+	Workers int
+	// Usage counts the API calls made by the client, if it is not nil.
+	Usage *Usage
 }
 
-// NewClient returns a new Client.
-func NewClient(host, key string, threshold int) *Client {
+// Limiter waits until an API call can be made. It is implemented by rate.Limiter and shared.Limiter.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// NewClient returns a new Client which makes at most callsPerSecond API calls per second.
+func NewClient(host, key string, threshold, callsPerSecond, workers int) *Client {
 	return &Client{
 		Client:    http.DefaultClient,
-		limiter:   rate.NewLimiter(rate.Limit(LimiterRate), LimiterRate),
+		limiter:   rate.NewLimiter(rate.Limit(callsPerSecond), callsPerSecond),
 		Host:      host,
 		Key:       key,
 		Threshold: threshold,
+		Workers:   workers,
+		Usage:     NewUsage(),
 	}
 }
 
+// UseShared makes the client wait on a limiter shared with other toolkit processes on this computer,
+// instead of its own, and share the number of API calls remaining for the day with them.
+func (c *Client) UseShared(l *shared.Limiter) {
+	c.limiter = l
+	c.shared = l
+}
+
 // ThresholdReachedError is an error returned when the API remaining call limit has been reached.
 type ThresholdReachedError struct {
 	// Remaining is the number of calls remaining.
@@ -80,36 +102,82 @@ func (e ThresholdReachedError) Error() string {
 	return fmt.Sprintf("call threshold of %v reached, %v calls remaining", e.Threshold, e.Remaining)
 }
 
-// CheckAPIandKey ensures the API is available and that the key provided has the right permissions.
-func (c *Client) CheckAPIandKey(ctx context.Context, readAccess, writeAccess []string) error {
-	for _, endpoint := range readAccess {
-		r, err := http.NewRequest("GET", endpoint+"/test", nil)
-		if err != nil {
-			return err
-		}
-		_, err = c.Do(ctx, r)
-		if err != nil {
-			return err
-		}
+// StatusError is an error returned when the API responds with an unexpected HTTP status code.
+type StatusError struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the URL of the request.
+	URL string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Body is the body of the response, which usually describes the error.
+	Body string
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("%v %v failed [%v]\n%v", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// IsNotFound returns true if the error was caused by the API responding with a 404 Not Found status.
+func IsNotFound(err error) bool {
+	var status *StatusError
+	return errors.As(err, &status) && status.StatusCode == http.StatusNotFound
+}
+
+// Redacted replaces the key in logs and error messages.
+const Redacted = "[REDACTED]"
+
+// redact returns s with any copies of the Key replaced.
+func (c *Client) redact(s string) string {
+	if c.Key == "" {
+		return s
 	}
-	for _, endpoint := range writeAccess {
-		r, err := http.NewRequest("POST", endpoint+"/test", nil)
-		if err != nil {
-			return err
-		}
-		_, err = c.Do(ctx, r)
-		if err != nil {
-			return err
-		}
+	return strings.ReplaceAll(s, c.Key, Redacted)
+}
+
+// RedactedError wraps an error whose message contained the API key.
+type RedactedError struct {
+	// Message is the message of the wrapped error, with the key replaced.
+	Message string
+	err     error
+}
+
+func (e RedactedError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the wrapped error, so errors.Is and errors.As can inspect it.
+func (e RedactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns an error with no copies of the Key in its message.
+func (c *Client) redactError(err error) error {
+	if err == nil || c.Key == "" {
+		return err
+	}
+	status, ok := err.(*StatusError)
+	if ok {
+		return &StatusError{status.Method, c.redact(status.URL), status.StatusCode, c.redact(status.Body)}
 	}
-	return nil
+	if !strings.Contains(err.Error(), c.Key) {
+		return err
+	}
+	return &RedactedError{c.redact(err.Error()), err}
 }
 
 // Do makes HTTP requests with the Client to the Host using the Key.
 // If a request returns an error, it is retried until RequestTimeout is reached.
 // The response bodies are copied or drained, then closed.
+// The Key is redacted from returned errors and logged messages.
 // See https://golang.org/pkg/net/http/#Client.Do
 func (c *Client) Do(ctx context.Context, r *http.Request) (body []byte, err error) {
+	body, err = c.do(ctx, r)
+	return body, c.redactError(err)
+}
+
+// do makes the HTTP request for Do.
+func (c *Client) do(ctx context.Context, r *http.Request) (body []byte, err error) {
 	// Create a new context with a timeout so we don't retry forever.
 	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
 	defer cancel()
@@ -133,9 +201,21 @@ func (c *Client) Do(ctx context.Context, r *http.Request) (body []byte, err erro
 			// The select statement chooses one case at random if multiple are ready.
 			// It is therefore possible that the context is cancelled.
 			// Wait on the rate limiter. This method also checks to see if the context is cancelled.
-			err = c.limiter.Wait(ctx)
-			if err != nil {
-				return body, fmt.Errorf("%v %v: %w", r.Method, r.URL.String(), err)
+			// Clients built without NewClient (in tests, for example) have no limiter.
+			if c.limiter != nil {
+				err = c.limiter.Wait(ctx)
+				if err != nil {
+					return body, fmt.Errorf("%v %v: %w", r.Method, r.URL.String(), err)
+				}
+			}
+			// Other processes sharing the limiter may have brought the remaining calls below the threshold.
+			if c.shared != nil {
+				rem, found, err := c.shared.Remaining()
+				if err != nil {
+					log.Printf("WARNING: Reading the shared remaining API calls failed, %v.\n", err)
+				} else if found && rem <= c.Threshold {
+					return body, &ThresholdReachedError{rem, c.Threshold}
+				}
 			}
 			// Make the request using the embedded http.Client.
 			resp, err := c.Client.Do(r)
@@ -150,7 +230,7 @@ func (c *Client) Do(ctx context.Context, r *http.Request) (body []byte, err erro
 			if err != nil {
 				// "On error, any Response can be ignored." No need to drain and close the body.
 				// Log is safe to use concurrently.
-				log.Printf("ERROR: Call to API failed, %v.\n", err)
+				log.Printf("ERROR: Call to API failed, %v.\n", c.redact(err.Error()))
 				backoff++
 				log.Printf("Retrying in %v seconds...\n", backoff)
 				// Loop again.
@@ -173,22 +253,35 @@ func (c *Client) Do(ctx context.Context, r *http.Request) (body []byte, err erro
 			// return a custom error called ThresholdReachedError, which can be checked later using
 			// errors.As().
 			rem, err := strconv.Atoi(resp.Header.Get("X-Exl-Api-Remaining"))
+			if c.Usage != nil {
+				c.Usage.Record(r.Method, r.URL.Path, rem, err == nil)
+			}
+			if c.shared != nil && err == nil {
+				recordErr := c.shared.Record(rem)
+				if recordErr != nil {
+					log.Printf("WARNING: Sharing the remaining API calls failed, %v.\n", recordErr)
+				}
+			}
 			if err == nil && rem <= c.Threshold {
 				return body, &ThresholdReachedError{rem, c.Threshold}
 
 			}
 			// The Alma API always returns a 200 status on success, except for a successful DELETE, which returns 204.
 			if (r.Method == "DELETE" && resp.StatusCode != 204) || (r.Method != "DELETE" && resp.StatusCode != 200) {
-				return body, fmt.Errorf("%v %v failed [%v]\n%v", r.Method, r.URL.String(), resp.StatusCode, string(body))
+				return body, &StatusError{r.Method, r.URL.String(), resp.StatusCode, string(body)}
 			}
 			return body, nil
 		}
 	}
 }
 
-// StartWorkers starts NumCPU workers in a worker pool which run jobs from the jobs channel until it is closed.
-func StartWorkers(wg *sync.WaitGroup, jobs <-chan func()) {
-	for worker := 0; worker < runtime.NumCPU(); worker++ {
+// StartWorkers starts workers in a worker pool which run jobs from the jobs channel until it is closed.
+// If workers is zero or less, NumCPU workers are started.
+func StartWorkers(wg *sync.WaitGroup, jobs <-chan func(), workers int) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	for worker := 0; worker < workers; worker++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -200,7 +293,7 @@ func StartWorkers(wg *sync.WaitGroup, jobs <-chan func()) {
 }
 
 // StartConcurrent initializes the context, mutexes, job channel, wait group, and progress bar for concurrent job processing.
-func StartConcurrent(ctx context.Context, numJobs int, desc string) (context.Context, context.CancelFunc, *sync.Mutex, *sync.Mutex, chan<- func(), *sync.WaitGroup, *progressbar.ProgressBar) {
+func (c Client) StartConcurrent(ctx context.Context, numJobs int, desc string) (context.Context, context.CancelFunc, *sync.Mutex, *sync.Mutex, chan<- func(), *sync.WaitGroup, *progressbar.ProgressBar) {
 	ctx, cancel := context.WithCancel(ctx)
 	// Errors Mux
 	em := &sync.Mutex{}
@@ -208,7 +301,7 @@ func StartConcurrent(ctx context.Context, numJobs int, desc string) (context.Con
 	om := &sync.Mutex{}
 	jobs := make(chan func())
 	wg := &sync.WaitGroup{}
-	StartWorkers(wg, jobs)
+	StartWorkers(wg, jobs, c.Workers)
 	bar := DefaultProgressBar(numJobs)
 	bar.Describe(desc)
 	return ctx, cancel, em, om, jobs, wg, bar
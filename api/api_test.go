@@ -7,15 +7,26 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"context"
+
+	"github.com/cu-library/almatoolkit/shared"
 )
 
 // TestDoAuthorizationKey checks that the Authorization header is set when making API calls.
@@ -175,3 +186,892 @@ func TestStatusCode(t *testing.T) {
 		}
 	}
 }
+
+// TestCodeTablesMissing checks that code tables which are not found are reported as missing, not as errors.
+func TestCodeTablesMissing(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/almaws/v1/conf/code-tables/Retired" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, "<code_table><name>Current</name></code_table>")
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+	tables, missing, errs := c.CodeTables(context.Background(), []string{"Current", "Retired"})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(tables) != 1 || tables[0].Name != "Current" {
+		t.Fatalf("unexpected tables: %v", tables)
+	}
+	if len(missing) != 1 || missing[0] != "Retired" {
+		t.Fatalf("unexpected missing tables: %v", missing)
+	}
+}
+
+// TestMappingTableUpdate checks that the columns and elements of a mapping table which aren't modelled are sent back.
+func TestMappingTableUpdate(t *testing.T) {
+	tableXML := `<mapping_table><name>Test</name><enabled_for>ALL</enabled_for>` +
+		`<rows><row><column0>A</column0><column1>B</column1><column2></column2><column3>C &amp; D</column3><enabled>true</enabled></row></rows>` +
+		`</mapping_table>`
+	var put string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			put = string(body)
+		}
+		fmt.Fprint(w, tableXML)
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	table, err := c.MappingTable(context.Background(), "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 1 || len(table.Rows[0].Other) != 1 || table.Rows[0].Other[0].Text != "C & D" {
+		t.Fatalf("unexpected rows: %+v", table.Rows)
+	}
+	table.Rows[0].Column1 = "E"
+	_, err = c.MappingTableUpdate(context.Background(), table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<column1>E</column1>", "<column3>C &amp; D</column3>", "<enabled_for>ALL</enabled_for>"} {
+		if !strings.Contains(put, want) {
+			t.Fatalf("%v is missing from the PUT table:\n%v", want, put)
+		}
+	}
+}
+
+// TestDoRedactsKey checks that the key is not in errors built from the request URL and response body.
+func TestDoRedactsKey(t *testing.T) {
+	key := "secretkey123"
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid API Key: %v", key)
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+		Key:    key,
+	}
+	r, err := http.NewRequest("GET", "/?apikey="+key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Do(context.Background(), r)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), key) {
+		t.Fatalf("key found in error message: %v", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a StatusError with a 400 status, got %v", err)
+	}
+}
+
+// TestCheckCapabilities checks that a key without a permission returns a MissingCapabilityError naming it.
+func TestCheckCapabilities(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/almaws/v1/bibs/test" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "API-key not defined or not configured to allow this API.")
+			return
+		}
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+	err = c.CheckCapabilities(context.Background(), []Capability{ConfRead, BibsRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.CheckCapabilities(context.Background(), []Capability{ConfRead, BibsWrite})
+	var missing *MissingCapabilityError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected a MissingCapabilityError, got %v", err)
+	}
+	if missing.Capability != BibsWrite || !strings.Contains(err.Error(), "Bibs Read/write") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// TestCallFormats checks that Call negotiates the format, and that the same model can be read from JSON and XML.
+func TestCallFormats(t *testing.T) {
+	responses := map[string]string{
+		"application/json": `{"library":[{"code":"MAIN","name":"Main Library","resource_sharing":true,` +
+			`"campus":{"value":"MAIN","desc":"Main Campus"},"link":"https://example.com/MAIN"}]}`,
+		"application/xml": `<libraries><library link="https://example.com/MAIN"><code>MAIN</code><name>Main Library</name>` +
+			`<resource_sharing>true</resource_sharing><campus desc="Main Campus">MAIN</campus></library></libraries>`,
+	}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[r.Header.Get("Accept")])
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+	for _, format := range []Format{JSON, XML} {
+		libraries := Libraries{}
+		_, err := c.Call(context.Background(), "GET", "/almaws/v1/conf/libraries", format, nil, &libraries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(libraries.Libraries) != 1 {
+			t.Fatalf("%v: expected one library, got %v", format, len(libraries.Libraries))
+		}
+		library := libraries.Libraries[0]
+		if library.Code != "MAIN" || !library.ResourceSharing || library.Campus.Text != "MAIN" ||
+			library.Campus.Desc != "Main Campus" || library.Link != "https://example.com/MAIN" {
+			t.Fatalf("%v: unexpected library %+v", format, library)
+		}
+	}
+}
+
+// testHoldingXML is a holding record with elements, attributes, comments, and escapes which the Holding struct does not model.
+const testHoldingXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<holding link="https://example.com/holdings/2233">
+  <holding_id>2233</holding_id>
+  <created_by>import</created_by>
+  <created_date>2020-01-01Z</created_date>
+  <last_modified_by>someone</last_modified_by>
+  <suppress_from_publishing>false</suppress_from_publishing>
+  <!-- A comment Alma would never send, but which should survive. -->
+  <record>
+    <leader>00000nx  a2200000un 4500</leader>
+    <controlfield tag="001">2233</controlfield>
+    <datafield ind1="0" ind2=" " tag="852" extra="kept">
+      <subfield code="b">MAIN</subfield>
+      <subfield code="c">STACKS</subfield>
+      <subfield code="h">BR115.C5L43</subfield>
+      <subfield code="z">Rock &amp; roll &#34;quoted&#34;</subfield>
+    </datafield>
+    <datafield ind1=" " ind2=" " tag="866"><subfield code="a">v.1-10</subfield><subfield code="x"/></datafield>
+  </record>
+</holding>`
+
+// TestHoldingRoundTrip checks that updating a holding only changes the text of the elements which changed.
+func TestHoldingRoundTrip(t *testing.T) {
+	holding := Holding{}
+	err := xml.Unmarshal([]byte(testHoldingXML), &holding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holding.Raw = []byte(testHoldingXML)
+	unchanged, err := xml.Marshal(holding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := patchXML(holding.Raw, unchanged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(patched) != testHoldingXML {
+		t.Fatalf("unchanged holding was not identical after patching:\n%s", patched)
+	}
+	holding.Record.Datafield[0].Subfield[2].Text = "BR115 .C5 L43 <new>"
+	holding.Record.Datafield[1].Subfield[1].Text = "note"
+	modified, err := xml.Marshal(holding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err = patchXML(holding.Raw, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(testHoldingXML, "BR115.C5L43", "BR115 .C5 L43 &lt;new&gt;", 1)
+	expected = strings.Replace(expected, `<subfield code="x"/>`, `<subfield code="x">note</subfield>`, 1)
+	if string(patched) != expected {
+		t.Fatalf("unexpected patched holding:\n%s", patched)
+	}
+}
+
+// testItemXML is an item record with elements the Item struct does not model.
+const testItemXML = `<item link="https://example.com/items/2344">
+<bib_data link="https://example.com/bibs/99"><mms_id>99</mms_id><title>A Title</title><author>An Author</author><issn/></bib_data>
+<holding_data><holding_id>2233</holding_id><call_number>BR115 .C5 L43</call_number><in_temp_location>false</in_temp_location></holding_data>
+<item_data>
+	<pid>2344</pid>
+	<barcode>39000000001</barcode>
+	<physical_material_type desc="Book">BOOK</physical_material_type>
+	<library desc="Main Library">MAIN</library>
+	<location desc="Stacks">STACKS</location>
+	<internal_note_1>Don't lose me</internal_note_1>
+</item_data>
+</item>`
+
+// TestItemRoundTrip checks that patching the XML of an item only changes the text of the elements which changed.
+func TestItemRoundTrip(t *testing.T) {
+	item := Item{}
+	err := xml.Unmarshal([]byte(testItemXML), &item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []byte(testItemXML)
+	unchanged, err := xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := patchXML(raw, unchanged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(patched) != testItemXML {
+		t.Fatalf("unchanged item was not identical after patching:\n%s", patched)
+	}
+	item.Barcode = "39000000002"
+	item.Location.Text = "REF"
+	modified, err := xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err = patchXML(raw, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(testItemXML, "39000000001", "39000000002", 1)
+	expected = strings.Replace(expected, `<location desc="Stacks">STACKS</location>`, `<location desc="Stacks">REF</location>`, 1)
+	if string(patched) != expected {
+		t.Fatalf("unexpected patched item:\n%s", patched)
+	}
+	item.ProcessType.Text = "WORK_ORDER_DEPARTMENT"
+	modified, err = xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = patchXML(raw, modified)
+	if err == nil {
+		t.Fatal("expected an error when setting an element which is not in the original item")
+	}
+}
+
+// TestEndpoint checks that identifiers are removed from paths so calls can be counted per endpoint.
+func TestEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"/almaws/v1/bibs/99123/holdings/22456/items/23789":       "/almaws/v1/bibs/{id}/holdings/{id}/items/{id}",
+		"/almaws/v1/conf/libraries/MAIN/circ-desks":              "/almaws/v1/conf/libraries/{id}/circ-desks",
+		"/almaws/v1/conf/code-tables/RequestCancellationReasons": "/almaws/v1/conf/code-tables/{id}",
+		"/almaws/v1/conf/test":                                   "/almaws/v1/conf/test",
+		"/almaws/v1/bibs/test":                                   "/almaws/v1/bibs/test",
+		"/almaws/v1/users/jsmith/requests":                       "/almaws/v1/users/{id}/requests",
+		"/":                                                      "/",
+	}
+	for path, expected := range tests {
+		if got := Endpoint(path); got != expected {
+			t.Errorf("Endpoint(%v) = %v, expected %v", path, got, expected)
+		}
+	}
+}
+
+// TestUsage checks that calls made by the client are counted per method and endpoint.
+func TestUsage(t *testing.T) {
+	remaining := 1000
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining--
+		w.Header().Set("X-Exl-Api-Remaining", strconv.Itoa(remaining))
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+		Usage:  NewUsage(),
+	}
+	for _, path := range []string{"/almaws/v1/bibs/1/holdings", "/almaws/v1/bibs/2/holdings", "/almaws/v1/conf/libraries"} {
+		r, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.Do(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	calls := c.Usage.Calls()
+	if len(calls) != 2 || calls[0].Endpoint != "/almaws/v1/bibs/{id}/holdings" || calls[0].Calls != 2 || calls[1].Calls != 1 {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+	if c.Usage.Total() != 3 {
+		t.Fatalf("expected 3 calls, got %v", c.Usage.Total())
+	}
+	last, found := c.Usage.LastRemaining()
+	if !found || last != 997 {
+		t.Fatalf("unexpected remaining calls %v", last)
+	}
+}
+
+// TestDoSharedThreshold checks that remaining calls recorded by another process stop the client at the threshold.
+func TestDoSharedThreshold(t *testing.T) {
+	called := false
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	l, err := shared.NewLimiter(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := shared.NewLimiter(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.Record(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		Client:    ts.Client(),
+		Host:      tsURL.Host,
+		Threshold: 100,
+	}
+	c.UseShared(l)
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Do(context.Background(), r)
+	var over *ThresholdReachedError
+	if !errors.As(err, &over) || over.Remaining != 10 {
+		t.Fatalf("expected a ThresholdReachedError, got %v", err)
+	}
+	if called {
+		t.Fatal("the API was called after the shared threshold was reached")
+	}
+}
+
+// testClient returns a client which calls the test server.
+// It is built without NewClient, so it has no rate limiter.
+func testClient(t *testing.T, ts *httptest.Server) *Client {
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+}
+
+// TestPOLines checks that every page of PO lines is returned.
+func TestPOLines(t *testing.T) {
+	total := 250
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("status") != "ACTIVE" || r.Header.Get("Accept") != "application/json" {
+			t.Errorf("unexpected request %v", r.URL)
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		lines := []string{}
+		for i := offset; i < offset+LimitParam && i < total; i++ {
+			lines = append(lines, fmt.Sprintf(`{"number":"POL-%v","price":{"sum":"%v.50","currency":{"value":"CAD"}}}`, i, i))
+		}
+		fmt.Fprintf(w, `{"po_line":[%v],"total_record_count":%v}`, strings.Join(lines, ","), total)
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	lines, errs := c.POLines(context.Background(), "ACTIVE", "")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(lines) != total {
+		t.Fatalf("expected %v lines, got %v", total, len(lines))
+	}
+	for _, line := range lines {
+		if line.Number == "POL-249" && line.Price.Sum != "249.50" {
+			t.Fatalf("unexpected price %v", line.Price.Sum)
+		}
+	}
+}
+
+// TestPOLineUpdate checks that fields not in the POLine struct are sent back unchanged.
+func TestPOLineUpdate(t *testing.T) {
+	original := `{"number":"POL-1","status":{"value":"ACTIVE","desc":"Active"},"price":{"sum":12.5,"currency":{"value":"CAD"}},` +
+		`"unknown_field":{"nested":[1,2,3],"big":12345678901234567890},"expected_receipt_date":"2020-01-01Z",` +
+		`"fund_distribution":[{"fund_code":{"value":"BOOKS"},"percent":60,"unknown":"a"},{"fund_code":{"value":"SERIALS"},"percent":40,"unknown":"b"}],` +
+		`"note":[{"note_text":"First","created_by":"someone"}]}`
+	var sent map[string]interface{}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			d := json.NewDecoder(bytes.NewReader(body))
+			d.UseNumber()
+			err = d.Decode(&sent)
+			if err != nil {
+				t.Error(err)
+			}
+			w.Write(body)
+			return
+		}
+		fmt.Fprint(w, original)
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	line, err := c.POLine(context.Background(), "POL-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line.ExpectedReceiptDate = "2021-06-01Z"
+	line.FundDistribution[1].Percent = "50"
+	line.Note = append(line.Note, line.Note[0])
+	line.Note[1].NoteText = "Second"
+	updated, err := c.POLineUpdate(context.Background(), line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ExpectedReceiptDate != "2021-06-01Z" || updated.Price.Sum != "12.5" {
+		t.Fatalf("unexpected updated line %+v", updated)
+	}
+	unknown, ok := sent["unknown_field"].(map[string]interface{})
+	if !ok || fmt.Sprint(unknown["big"]) != "12345678901234567890" || fmt.Sprint(unknown["nested"]) != "[1 2 3]" {
+		t.Fatalf("unknown field was not kept: %v", sent["unknown_field"])
+	}
+	status, ok := sent["status"].(map[string]interface{})
+	if !ok || status["desc"] != "Active" {
+		t.Fatalf("status was not kept: %v", sent["status"])
+	}
+	// Elements of arrays are merged by position.
+	distribution, ok := sent["fund_distribution"].([]interface{})
+	if !ok || len(distribution) != 2 {
+		t.Fatalf("unexpected fund distribution: %v", sent["fund_distribution"])
+	}
+	second, ok := distribution[1].(map[string]interface{})
+	if !ok || fmt.Sprint(second["percent"]) != "50" || second["unknown"] != "b" {
+		t.Fatalf("the unknown field of the fund distribution was not kept: %v", distribution[1])
+	}
+	notes, ok := sent["note"].([]interface{})
+	if !ok || len(notes) != 2 {
+		t.Fatalf("unexpected notes: %v", sent["note"])
+	}
+	first, ok := notes[0].(map[string]interface{})
+	if !ok || first["note_text"] != "First" || first["created_by"] != "someone" {
+		t.Fatalf("the unknown field of the note was not kept: %v", notes[0])
+	}
+	added, ok := notes[1].(map[string]interface{})
+	if !ok || added["note_text"] != "Second" {
+		t.Fatalf("unexpected added note: %v", notes[1])
+	}
+}
+
+// TestFunds checks that the full view of the funds is requested, and that the balances are read.
+func TestFunds(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("view") != "full" {
+			t.Errorf("the full view was not requested, %v", r.URL)
+		}
+		if q.Get("mode") != "ALL" || q.Get("fiscal_period") != "2021" || q.Get("entity_type") != "FUND" {
+			t.Errorf("unexpected request %v", r.URL)
+		}
+		fmt.Fprint(w, `{"fund":[{"code":"BOOKS","allocated_balance":{"sum":1000,"currency":{"value":"CAD"}},`+
+			`"available_balance":{"sum":"250.25","currency":{"value":"CAD"}}}],"total_record_count":1}`)
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	funds, errs := c.Funds(context.Background(), FundsQuery{FiscalPeriod: "2021", EntityType: "FUND"})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(funds) != 1 || funds[0].AllocatedBalance.Sum != "1000" || funds[0].AvailableBalance.Sum != "250.25" {
+		t.Fatalf("unexpected funds %+v", funds)
+	}
+}
+
+// testAnalyticsSchema is the rowset schema of the first page of an Analytics report.
+const testAnalyticsSchema = `<xsd:schema xmlns:xsd="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:schemas-microsoft-com:xml-analysis:rowset" xmlns:saw-sql="urn:saw-sql">
+<xsd:complexType name="Row"><xsd:sequence>
+<xsd:element minOccurs="0" maxOccurs="1" name="Column0" type="xsd:int" saw-sql:type="integer" saw-sql:columnHeading="0"/>
+<xsd:element minOccurs="0" maxOccurs="1" name="Column1" type="xsd:string" saw-sql:type="varchar" saw-sql:columnHeading="Barcode"/>
+<xsd:element minOccurs="0" maxOccurs="1" name="Column2" type="xsd:double" saw-sql:type="double" saw-sql:columnHeading="Loans"/>
+<xsd:element minOccurs="0" maxOccurs="1" name="Column3" type="xsd:date" saw-sql:type="date" saw-sql:columnHeading="Creation Date"/>
+</xsd:sequence></xsd:complexType></xsd:schema>`
+
+// TestAnalyticsReport checks that every page is requested with the resumption token, and that columns are typed.
+func TestAnalyticsReport(t *testing.T) {
+	pages := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		pages++
+		switch pages {
+		case 1:
+			if q.Get("path") != "/shared/Reports/Items" || q.Get("col_names") != "true" || q.Get("token") != "" {
+				t.Errorf("unexpected first request %v", r.URL)
+			}
+			fmt.Fprintf(w, `<report><QueryResult><ResumptionToken>TOKEN</ResumptionToken><IsFinished>false</IsFinished><ResultXml>`+
+				`<rowset xmlns="urn:schemas-microsoft-com:xml-analysis:rowset">%v`+
+				`<Row><Column0>0</Column0><Column1>39000000001</Column1><Column2>4</Column2><Column3>2020-01-02</Column3></Row>`+
+				`</rowset></ResultXml></QueryResult></report>`, testAnalyticsSchema)
+		case 2:
+			// Analytics can return pages without rows while the report is running.
+			fmt.Fprint(w, `<report><QueryResult><IsFinished>false</IsFinished><ResultXml>`+
+				`<rowset xmlns="urn:schemas-microsoft-com:xml-analysis:rowset"></rowset></ResultXml></QueryResult></report>`)
+		default:
+			if q.Get("token") != "TOKEN" || q.Get("path") != "" {
+				t.Errorf("unexpected request %v", r.URL)
+			}
+			fmt.Fprint(w, `<report><QueryResult><IsFinished>true</IsFinished><ResultXml>`+
+				`<rowset xmlns="urn:schemas-microsoft-com:xml-analysis:rowset">`+
+				`<Row><Column0>0</Column0><Column1>39000000002</Column1><Column2>0.5</Column2></Row>`+
+				`</rowset></ResultXml></QueryResult></report>`)
+		}
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	columns, rows, err := c.AnalyticsReport(context.Background(), AnalyticsQuery{Path: "/shared/Reports/Items", Limit: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 || len(columns) != 4 || len(rows) != 2 {
+		t.Fatalf("expected 3 pages, 4 columns, and 2 rows, got %v, %v, and %v", pages, len(columns), len(rows))
+	}
+	if columns[3].Heading != "Creation Date" || columns[3].Type != "date" || columns[3].SQLType != "date" || columns[3].Kind() != KindDate {
+		t.Fatalf("unexpected column %+v", columns[3])
+	}
+	values, err := rows[0].Values(columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[1] != "39000000001" || values[2] != float64(4) || !values[3].(time.Time).Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected values %v", values)
+	}
+	values, err = rows[1].Values(columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != int64(0) || values[2] != 0.5 || values[3] != nil {
+		t.Fatalf("unexpected values %v", values)
+	}
+	_, _, err = c.AnalyticsReport(context.Background(), AnalyticsQuery{Path: "/shared/Reports/Items", Limit: 30})
+	if err == nil {
+		t.Fatal("expected an error for a limit which is not a multiple of 25")
+	}
+}
+
+// TestJobRunAndWait checks that the job is run with the set parameter, and that the instance is polled until it finishes.
+func TestJobRunAndWait(t *testing.T) {
+	polls := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/almaws/v1/conf/jobs/M18" && r.URL.Query().Get("op") == "run":
+			job := Job{}
+			err := json.NewDecoder(r.Body).Decode(&job)
+			if err != nil {
+				t.Error(err)
+			}
+			if len(job.Parameters) != 2 || job.Parameters[1].Name.Value != "set_id" || job.Parameters[1].Value != "12345" {
+				t.Errorf("unexpected parameters %+v", job.Parameters)
+			}
+			fmt.Fprint(w, `{"id":"M18","additional_info":{"value":"Job no. 678 triggered","link":"/almaws/v1/conf/jobs/M18/instances/678"}}`)
+		case r.Method == "GET" && r.URL.Path == "/almaws/v1/conf/jobs/M18/instances/678":
+			polls++
+			if polls < 3 {
+				fmt.Fprintf(w, `{"id":"678","progress":%v,"status":{"value":"RUNNING"}}`, polls*40)
+				return
+			}
+			fmt.Fprint(w, `{"id":"678","progress":100,"status":{"value":"COMPLETED_SUCCESS"},`+
+				`"counter":[{"type":{"value":"label.new.items","desc":"Records processed"},"value":"12"}]}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	job := Job{ID: "M18", Parameters: []JobParameter{{Name: Value{Value: "task_name"}, Value: "Change physical items"}}}
+	job.SetParameter("set_id", "12345")
+	instanceID, err := c.JobRun(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instanceID != "678" {
+		t.Fatalf("unexpected instance ID %v", instanceID)
+	}
+	instance, err := c.JobInstanceWait(context.Background(), job.ID, instanceID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 || instance.Running() || !instance.Succeeded() {
+		t.Fatalf("unexpected instance %+v after %v polls", instance, polls)
+	}
+	if len(instance.Counters) != 1 || instance.Counters[0].Value != "12" {
+		t.Fatalf("unexpected counters %+v", instance.Counters)
+	}
+}
+
+// TestJobInstanceWaitRetries checks that failed checks on the instance are retried,
+// and that waiting stops with the instance ID in the error after JobPollFailures failures in a row.
+func TestJobInstanceWaitRetries(t *testing.T) {
+	polls := 0
+	failUntil := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls <= failUntil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"errorsExist":true}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"678","progress":100,"status":{"value":"COMPLETED_SUCCESS"}}`)
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	failUntil = JobPollFailures - 1
+	instance, err := c.JobInstanceWait(context.Background(), "M18", "678", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != JobPollFailures || !instance.Succeeded() {
+		t.Fatalf("unexpected instance %+v after %v polls", instance, polls)
+	}
+	polls = 0
+	failUntil = JobPollFailures
+	_, err = c.JobInstanceWait(context.Background(), "M18", "678", time.Millisecond)
+	statusErr := &StatusError{}
+	if !errors.As(err, &statusErr) || !strings.Contains(err.Error(), "instance 678") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if polls != JobPollFailures {
+		t.Fatalf("waiting stopped after %v polls, not %v", polls, JobPollFailures)
+	}
+}
+
+// TestJobRunFileAndEvents checks that the file is uploaded with the job, and that the events of the instance are listed.
+func TestJobRunFileAndEvents(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/almaws/v1/conf/jobs/S123":
+			err := r.ParseMultipartForm(1 << 20)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer file.Close()
+			contents, _ := ioutil.ReadAll(file)
+			if header.Filename != "vendor.mrc" || string(contents) != "MARC" || !strings.Contains(r.FormValue("job"), `"id":"S123"`) {
+				t.Errorf("unexpected upload %v %q %q", header.Filename, contents, r.FormValue("job"))
+			}
+			fmt.Fprint(w, `{"id":"S123","additional_info":{"link":"/almaws/v1/conf/jobs/S123/instances/9"}}`)
+		case r.Method == "GET" && r.URL.Path == "/almaws/v1/conf/jobs/S123/instances/9/events":
+			fmt.Fprint(w, `{"event":[{"type":{"value":"MD_IMPORT_RECORD_REJECTED","desc":"Record rejected"},`+
+				`"entity":[{"type":{"value":"BIB_MMS"},"value":"991234"}]}],"total_record_count":1}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	instanceID, err := c.JobRunFile(context.Background(), Job{ID: "S123"}, "vendor.mrc", []byte("MARC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, errs := c.JobInstanceEvents(context.Background(), "S123", instanceID)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(events) != 1 || events[0].Type.Value != "MD_IMPORT_RECORD_REJECTED" || events[0].Entities[0].Value != "991234" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+// TestItemMembersCreateRequest checks that the user request XML is POSTed to the requests of each item.
+func TestItemMembersCreateRequest(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || !strings.HasSuffix(r.URL.Path, "/requests") || r.Header.Get("Content-Type") != "application/xml" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		sent := UserRequest{}
+		err = xml.Unmarshal(body, &sent)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if sent.Type != "WORK_ORDER" || sent.SubType != "BINDING" || sent.TargetDestination != "BINDERY" {
+			t.Errorf("unexpected user request %s", body)
+		}
+		fmt.Fprint(w, `<user_request><request_id>77</request_id><request_type>WORK_ORDER</request_type></user_request>`)
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+	members := []Member{
+		{ID: "231", Link: "/almaws/v1/bibs/99/holdings/22/items/231"},
+		{ID: "232", Link: "/almaws/v1/bibs/99/holdings/22/items/232"},
+	}
+	request := UserRequest{Type: "WORK_ORDER", SubType: "BINDING", TargetDestination: "BINDERY"}
+	created, errs := c.ItemMembersCreateRequest(context.Background(), members, request)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(created) != 2 {
+		t.Fatalf("expected 2 requests, got %v", len(created))
+	}
+	for _, request := range created {
+		if request.ID != "77" || request.Link != request.Member.Link+"/requests/77" {
+			t.Fatalf("unexpected created request %+v", request)
+		}
+	}
+}
+
+// electronicServer returns a server which serves the JSON at one path, echoes PUTs, and accepts DELETEs.
+// The methods and paths of the requests, and the bodies of PUTs, are recorded.
+func electronicServer(t *testing.T, path, original string) (ts *httptest.Server, requests *[]string, sent *map[string]interface{}) {
+	requests = &[]string{}
+	sent = &map[string]interface{}{}
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+		if r.URL.Path != path {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, original)
+		case "PUT":
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = json.Unmarshal(body, sent)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			w.Write(body)
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	return ts, requests, sent
+}
+
+// TestElectronicCollection checks that collections are read, updated without losing fields, and deleted.
+func TestElectronicCollection(t *testing.T) {
+	path := "/almaws/v1/electronic/e-collections/61"
+	original := `{"id":"61","public_name":"Journals","type":{"value":"0","desc":"selective package"},"proxy_enabled":{"value":"true"}}`
+	ts, requests, sent := electronicServer(t, path, original)
+	defer ts.Close()
+	c := testClient(t, ts)
+	collection, err := c.ElectronicCollection(context.Background(), "61")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collection.PublicName != "Journals" || collection.Type.Desc != "selective package" {
+		t.Fatalf("unexpected collection %+v", collection)
+	}
+	collection.PublicNote = "Note"
+	updated, err := c.ElectronicCollectionUpdate(context.Background(), collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.PublicNote != "Note" || updated.ID != "61" {
+		t.Fatalf("unexpected updated collection %+v", updated)
+	}
+	proxy, ok := (*sent)["proxy_enabled"].(map[string]interface{})
+	if !ok || proxy["value"] != "true" {
+		t.Fatalf("unknown field was not kept: %v", *sent)
+	}
+	err = c.ElectronicCollectionDelete(context.Background(), updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.ElectronicCollectionDelete(context.Background(), ElectronicCollection{ID: "62"})
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error deleting a missing collection, got %v", err)
+	}
+	want := []string{"GET " + path, "PUT " + path, "DELETE " + path, "DELETE /almaws/v1/electronic/e-collections/62"}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Fatalf("unexpected requests\ngot:  %v\nwant: %v", *requests, want)
+	}
+}
+
+// TestElectronicService checks that services are read, updated without losing fields, and deleted.
+func TestElectronicService(t *testing.T) {
+	path := "/almaws/v1/electronic/e-collections/61/e-services/62"
+	original := `{"id":"62","description":"Full text","activation_status":{"value":"11"},"service_temporarily_unavailable":{"value":"false"}}`
+	ts, requests, sent := electronicServer(t, path, original)
+	defer ts.Close()
+	c := testClient(t, ts)
+	service, err := c.ElectronicService(context.Background(), "61", "62")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.Description != "Full text" || service.ActivationStatus.Value != Available || service.CollectionID != "61" {
+		t.Fatalf("unexpected service %+v", service)
+	}
+	service.ActivationStatus.Value = NotAvailable
+	updated, err := c.ElectronicServiceUpdate(context.Background(), service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ActivationStatus.Value != NotAvailable || updated.CollectionID != "61" {
+		t.Fatalf("unexpected updated service %+v", updated)
+	}
+	unavailable, ok := (*sent)["service_temporarily_unavailable"].(map[string]interface{})
+	if !ok || unavailable["value"] != "false" {
+		t.Fatalf("unknown field was not kept: %v", *sent)
+	}
+	err = c.ElectronicServiceDelete(context.Background(), updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GET " + path, "PUT " + path, "DELETE " + path}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Fatalf("unexpected requests\ngot:  %v\nwant: %v", *requests, want)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Capability is a permission an API key can be given on one area of the Alma API.
+// https://developers.exlibrisgroup.com/manage/keys/
+type Capability struct {
+	Area  string // The area of the API, like bibs or conf.
+	Path  string // The path of the area, which has a test endpoint.
+	Write bool   // Read/write permission is needed, not just read-only.
+}
+
+// The capabilities an API key can have.
+var (
+	AcqRead         = Capability{"Acquisitions", "/almaws/v1/acq", false}
+	AcqWrite        = Capability{"Acquisitions", "/almaws/v1/acq", true}
+	AnalyticsRead   = Capability{"Analytics", "/almaws/v1/analytics", false}
+	BibsRead        = Capability{"Bibs", "/almaws/v1/bibs", false}
+	BibsWrite       = Capability{"Bibs", "/almaws/v1/bibs", true}
+	ConfRead        = Capability{"Configuration", "/almaws/v1/conf", false}
+	ConfWrite       = Capability{"Configuration", "/almaws/v1/conf", true}
+	CoursesRead     = Capability{"Courses", "/almaws/v1/courses", false}
+	CoursesWrite    = Capability{"Courses", "/almaws/v1/courses", true}
+	ElectronicRead  = Capability{"Electronic", "/almaws/v1/electronic", false}
+	ElectronicWrite = Capability{"Electronic", "/almaws/v1/electronic", true}
+	PartnersRead    = Capability{"Resource Sharing Partners", "/almaws/v1/partners", false}
+	PartnersWrite   = Capability{"Resource Sharing Partners", "/almaws/v1/partners", true}
+	TaskListsRead   = Capability{"Task Lists", "/almaws/v1/task-lists", false}
+	TaskListsWrite  = Capability{"Task Lists", "/almaws/v1/task-lists", true}
+	UsersRead       = Capability{"Users", "/almaws/v1/users", false}
+	UsersWrite      = Capability{"Users", "/almaws/v1/users", true}
+)
+
+// AllCapabilities lists every capability, grouped by area.
+var AllCapabilities = []Capability{
+	AcqRead, AcqWrite,
+	AnalyticsRead,
+	BibsRead, BibsWrite,
+	ConfRead, ConfWrite,
+	CoursesRead, CoursesWrite,
+	ElectronicRead, ElectronicWrite,
+	PartnersRead, PartnersWrite,
+	TaskListsRead, TaskListsWrite,
+	UsersRead, UsersWrite,
+}
+
+// Permission returns the name of the permission, as it is shown on the Developer Network.
+func (c Capability) Permission() string {
+	if c.Write {
+		return c.Area + " Read/write"
+	}
+	return c.Area + " Read-only"
+}
+
+func (c Capability) String() string {
+	return c.Permission()
+}
+
+// MissingCapabilityError is returned when the API key does not have a capability.
+type MissingCapabilityError struct {
+	Capability Capability
+	err        error
+}
+
+func (e MissingCapabilityError) Error() string {
+	return fmt.Sprintf("the API key does not have the '%v' permission, "+
+		"which can be added at https://developers.exlibrisgroup.com/manage/keys/", e.Capability.Permission())
+}
+
+// Unwrap returns the error returned by the test endpoint.
+func (e MissingCapabilityError) Unwrap() error {
+	return e.err
+}
+
+// CheckCapability ensures the key has the capability, using the test endpoint of the capability's area.
+// Read-only capabilities are tested with GET, read/write capabilities with POST.
+func (c *Client) CheckCapability(ctx context.Context, capability Capability) error {
+	method := "GET"
+	if capability.Write {
+		method = "POST"
+	}
+	r, err := http.NewRequest(method, capability.Path+"/test", nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.Do(ctx, r)
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch status.StatusCode {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return &MissingCapabilityError{capability, err}
+		}
+	}
+	return err
+}
+
+// CheckCapabilities ensures the API is available and that the key has all the capabilities.
+func (c *Client) CheckCapabilities(ctx context.Context, capabilities []Capability) error {
+	for _, capability := range capabilities {
+		err := c.CheckCapability(ctx, capability)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CircDesk stores data about a circulation desk in a library.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_circ_desk.xsd/
+type CircDesk struct {
+	XMLName     xml.Name `xml:"circ_desk"`
+	Link        string   `xml:"link,attr"`
+	Code        string   `xml:"code"`
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	Primary     string   `xml:"primary"`
+	ReadingRoom string   `xml:"reading_room"`
+	HoldShelf   string   `xml:"hold_shelf"`
+	Locations   []struct {
+		Code string `xml:"code"`
+		Name string `xml:"name"`
+	} `xml:"locations>location"`
+	// Library is an optional field for the code of the library the desk is in.
+	Library string `xml:"-"`
+}
+
+// CircDesks stores data about the circulation desks in a library.
+// This is a little different than []CircDesk for XML unmarshalling.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_circ_desks.xsd/
+type CircDesks struct {
+	XMLName          xml.Name   `xml:"circ_desks"`
+	TotalRecordCount string     `xml:"total_record_count,attr"`
+	CircDesks        []CircDesk `xml:"circ_desk"`
+}
+
+// CircDesk returns the circ desk with the given code, and whether it was found.
+func (d CircDesks) CircDesk(code string) (desk CircDesk, found bool) {
+	for _, desk := range d.CircDesks {
+		if desk.Code == code {
+			return desk, true
+		}
+	}
+	return desk, false
+}
+
+// Primary returns the library's primary circ desk, and whether one was found.
+func (d CircDesks) Primary() (desk CircDesk, found bool) {
+	for _, desk := range d.CircDesks {
+		if desk.Primary == "true" {
+			return desk, true
+		}
+	}
+	return desk, false
+}
+
+// CircDesks returns the summaries of the circulation desks in the library.
+// The locations attached to each desk are not included, use CircDesk to retrieve them.
+func (c Client) CircDesks(ctx context.Context, library string) (desks CircDesks, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/conf/libraries/"+url.PathEscape(library)+"/circ-desks", nil)
+	if err != nil {
+		return desks, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return desks, err
+	}
+	err = xml.Unmarshal(body, &desks)
+	if err != nil {
+		return desks, fmt.Errorf("unmarshalling circ desks XML failed: %w\n%v", err, string(body))
+	}
+	for i := range desks.CircDesks {
+		desks.CircDesks[i].Library = library
+	}
+	return desks, nil
+}
+
+// CircDesk returns the circulation desk in the library, including its attached locations.
+func (c Client) CircDesk(ctx context.Context, library, code string) (desk CircDesk, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/conf/libraries/"+url.PathEscape(library)+"/circ-desks/"+url.PathEscape(code), nil)
+	if err != nil {
+		return desk, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return desk, err
+	}
+	err = xml.Unmarshal(body, &desk)
+	if err != nil {
+		return desk, fmt.Errorf("unmarshalling circ desk XML failed: %w\n%v", err, string(body))
+	}
+	desk.Library = library
+	return desk, nil
+}
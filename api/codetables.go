@@ -7,11 +7,13 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 // CodeTable stores data about codes and their related descriptions.
@@ -39,177 +41,226 @@ type CodeTable struct {
 			Desc string `xml:"desc,attr"`
 		} `xml:"library_id"`
 	} `xml:"scope"`
-	Rows []struct {
-		Text        string `xml:",chardata"`
-		Code        string `xml:"code"`
-		Description string `xml:"description"`
-		Default     string `xml:"default"`
-		Enabled     string `xml:"enabled"`
-	} `xml:"rows>row"`
+	Rows []CodeTableRow `xml:"rows>row"`
+	// Other stores the elements of the table which aren't modelled above.
+	Other []XMLElement `xml:",any"`
 }
 
-// CodeTables returns all known code tables.
-func (c Client) CodeTables(ctx context.Context) (tables []CodeTable, errs []error) {
-	names := []string{
-		"accessionPlacementsOptions",
-		"AcqItemSourceType",
-		"AcquisitionMethod",
-		"ActiveResourcesTypes",
-		"AddNewUserOptions",
-		"AdminURIType",
-		"ARTEmailDeliveryKeywords",
-		"ARTEmailQueriesKeywords",
-		"ARTEmailServiceKeywords",
-		"AssertionCodes",
-		"BaseStatus",
-		"BLDSSDigitalFormats",
-		"BooleanYesNo",
-		"CalendarRecordStatuses",
-		"CalendarRecordsTypes",
-		"CallNumberType",
-		"CampusListSearchableColumns",
-		"CatalogerLevel",
-		"CitationAttributes",
-		"CitationAttributesTypes",
-		"CitationCopyRights",
-		"CollectionAccessType",
-		"ContentStructureStatus",
-		"CounterPlatform",
-		"CountryCodes",
-		"CourseTerms",
-		"CoverageInUse",
-		"crossRefEnabled",
-		"CrossRefSupported",
-		"Currency_CT",
-		"DaysOfWeek",
-		"DigitalRepresentationBaseStatus",
-		"EDINamingConvention",
-		"EdiPreference",
-		"EdiType",
-		"ElectronicBaseStatus",
-		"electronicMaterialType",
-		"ElectronicPortfolioBaseStatus",
-		"ExpiryType",
-		"ExternalSystemTypes",
-		"FineFeeTransactionType",
-		"FTPMode",
-		"FTPSend",
-		"FundType",
-		"Genders",
-		"GroupProxyEnabled",
-		"HFrUserFinesFees.fineFeeStatus",
-		"HFrUserFinesFees.fineFeeType",
-		"HFrUserRoles.roleType",
-		"HfundLedger.status",
-		"HFundsTransactionItem.reportingCode",
-		"HItemLoan.processStatus",
-		"HLicense.status",
-		"HLicense.type",
-		"HLocation.locationType",
-		"HPaTaskChain.businessEntity",
-		"HPaTaskChain.type",
-		"ImplementedAuthMethod",
-		"IntegrationTypes",
-		"InvoiceApprovalStatus",
-		"InvoiceCreationForm",
-		"InvoiceLineStatus",
-		"InvoiceLinesTypes",
-		"InvoiceStatus",
-		"IpAddressRegMethod",
-		"isAggregator",
-		"IsFree",
-		"ItemPhysicalCondition",
-		"ItemPolicy",
-		"JobsApiJobTypes",
-		"jobScheduleNames",
-		"JobTitles",
-		"LevelOfService",
-		"LibraryNoticesOptInDisplay",
-		"LicenseReviewStatuses",
-		"LicenseStorageLocation",
-		"LicenseTerms",
-		"LicenseTermsAndTypes",
-		"LinkingLevel",
-		"LinkResolverPlugin",
-		"marcLanguage",
-		"Months",
-		"MovingWallOperator",
-		"NoteTypes",
-		"OwnerHierarchy",
-		"PartnerSystemTypes",
-		"PaymentMethod",
-		"PaymentStatus",
-		"PhysicalMaterialType",
-		"PhysicalReadingListCitationTypes",
-		"POLineStatus",
-		"PortfolioAccessType",
-		"PPRSourceType",
-		"PR_CitationType",
-		"PR_RejectReasons",
-		"PR_RequestedFormat",
-		"PROCESSTYPE",
-		"provenanceCodes",
-		"PurchaseRequestStatus",
-		"PurchaseType",
-		"ReadingListCitationSecondaryTypes",
-		"ReadingListCitationTypes",
-		"ReadingListRLStatuses",
-		"ReadingListStatuses",
-		"ReadingListVisibilityStatuses",
-		"RecurrenceType",
-		"ReminderStatuses",
-		"ReminderTypes",
-		"RenewalCycle",
-		"representationEntityType",
-		"RepresentationUsageType",
-		"RequestCancellationReasons",
-		"RequestFormats",
-		"RequestOptions",
-		"ResourceSharingCopyrightsStatus",
-		"ResourceSharingLanguages",
-		"ResourceSharingRequestSendMethod",
-		"SecondReportingCode",
-		"ServiceType",
-		"SetContentType",
-		"SetPrivacy",
-		"SetStatus",
-		"SetType",
-		"ShippingMethod",
-		"Sub Systems",
-		"SystemJobReportAlertMessage",
-		"systemJobStatus",
-		"TagTypes",
-		"ThirdReportingCode",
-		"UsageStatsDeliveryMethod",
-		"UsageStatsFormat",
-		"UsageStatsFrequency",
-		"UserAddressTypes",
-		"UserBlockDescription",
-		"UserBlockTypes",
-		"UserEmailTypes",
-		"UserGroups",
-		"UserIdentifierTypes",
-		"UserPhoneTypes",
-		"UserPreferredLanguage",
-		"UserRoleStatus",
-		"UserStatCategories",
-		"UserStatisticalTypes",
-		"UserUserType",
-		"UserWebAddressTypes",
-		"VATType",
-		"VendorReferenceNumberType",
-		"VendorSearchStatusFilter",
-		"WebhookEvents",
-		"WebhooksActionType",
-		"WorkbenchPaymentMethod",
+// CodeTableRow stores data about a code and its related description.
+type CodeTableRow struct {
+	Code        string `xml:"code"`
+	Description string `xml:"description"`
+	Default     string `xml:"default"`
+	Enabled     string `xml:"enabled"`
+	// Other stores the other fields of the row.
+	Other []XMLText `xml:",any"`
+}
+
+// CodeTableList stores the names and descriptions of the code tables configured for the Institution.
+type CodeTableList struct {
+	XMLName    xml.Name `xml:"code_tables" json:"-"`
+	CodeTables []struct {
+		Link        string `xml:"link,attr" json:"link"`
+		Name        string `xml:"name" json:"name"`
+		Description string `xml:"description" json:"description"`
+	} `xml:"code_table" json:"code_table"`
+}
+
+// KnownCodeTables are the names of the code tables listed in
+// https://developers.exlibrisgroup.com/blog/almas-code-tables-api-list-of-code-tables/
+// It is used when the list of code tables can't be retrieved from the API.
+var KnownCodeTables = []string{
+	"accessionPlacementsOptions",
+	"AcqItemSourceType",
+	"AcquisitionMethod",
+	"ActiveResourcesTypes",
+	"AddNewUserOptions",
+	"AdminURIType",
+	"ARTEmailDeliveryKeywords",
+	"ARTEmailQueriesKeywords",
+	"ARTEmailServiceKeywords",
+	"AssertionCodes",
+	"BaseStatus",
+	"BLDSSDigitalFormats",
+	"BooleanYesNo",
+	"CalendarRecordStatuses",
+	"CalendarRecordsTypes",
+	"CallNumberType",
+	"CampusListSearchableColumns",
+	"CatalogerLevel",
+	"CitationAttributes",
+	"CitationAttributesTypes",
+	"CitationCopyRights",
+	"CollectionAccessType",
+	"ContentStructureStatus",
+	"CounterPlatform",
+	"CountryCodes",
+	"CourseTerms",
+	"CoverageInUse",
+	"crossRefEnabled",
+	"CrossRefSupported",
+	"Currency_CT",
+	"DaysOfWeek",
+	"DigitalRepresentationBaseStatus",
+	"EDINamingConvention",
+	"EdiPreference",
+	"EdiType",
+	"ElectronicBaseStatus",
+	"electronicMaterialType",
+	"ElectronicPortfolioBaseStatus",
+	"ExpiryType",
+	"ExternalSystemTypes",
+	"FineFeeTransactionType",
+	"FTPMode",
+	"FTPSend",
+	"FundType",
+	"Genders",
+	"GroupProxyEnabled",
+	"HFrUserFinesFees.fineFeeStatus",
+	"HFrUserFinesFees.fineFeeType",
+	"HFrUserRoles.roleType",
+	"HfundLedger.status",
+	"HFundsTransactionItem.reportingCode",
+	"HItemLoan.processStatus",
+	"HLicense.status",
+	"HLicense.type",
+	"HLocation.locationType",
+	"HPaTaskChain.businessEntity",
+	"HPaTaskChain.type",
+	"ImplementedAuthMethod",
+	"IntegrationTypes",
+	"InvoiceApprovalStatus",
+	"InvoiceCreationForm",
+	"InvoiceLineStatus",
+	"InvoiceLinesTypes",
+	"InvoiceStatus",
+	"IpAddressRegMethod",
+	"isAggregator",
+	"IsFree",
+	"ItemPhysicalCondition",
+	"ItemPolicy",
+	"JobsApiJobTypes",
+	"jobScheduleNames",
+	"JobTitles",
+	"LevelOfService",
+	"LibraryNoticesOptInDisplay",
+	"LicenseReviewStatuses",
+	"LicenseStorageLocation",
+	"LicenseTerms",
+	"LicenseTermsAndTypes",
+	"LinkingLevel",
+	"LinkResolverPlugin",
+	"marcLanguage",
+	"Months",
+	"MovingWallOperator",
+	"NoteTypes",
+	"OwnerHierarchy",
+	"PartnerSystemTypes",
+	"PaymentMethod",
+	"PaymentStatus",
+	"PhysicalMaterialType",
+	"PhysicalReadingListCitationTypes",
+	"POLineStatus",
+	"PortfolioAccessType",
+	"PPRSourceType",
+	"PR_CitationType",
+	"PR_RejectReasons",
+	"PR_RequestedFormat",
+	"PROCESSTYPE",
+	"provenanceCodes",
+	"PurchaseRequestStatus",
+	"PurchaseType",
+	"ReadingListCitationSecondaryTypes",
+	"ReadingListCitationTypes",
+	"ReadingListRLStatuses",
+	"ReadingListStatuses",
+	"ReadingListVisibilityStatuses",
+	"RecurrenceType",
+	"ReminderStatuses",
+	"ReminderTypes",
+	"RenewalCycle",
+	"representationEntityType",
+	"RepresentationUsageType",
+	"RequestCancellationReasons",
+	"RequestFormats",
+	"RequestOptions",
+	"ResourceSharingCopyrightsStatus",
+	"ResourceSharingLanguages",
+	"ResourceSharingRequestSendMethod",
+	"SecondReportingCode",
+	"ServiceType",
+	"SetContentType",
+	"SetPrivacy",
+	"SetStatus",
+	"SetType",
+	"ShippingMethod",
+	"Sub Systems",
+	"SystemJobReportAlertMessage",
+	"systemJobStatus",
+	"TagTypes",
+	"ThirdReportingCode",
+	"UsageStatsDeliveryMethod",
+	"UsageStatsFormat",
+	"UsageStatsFrequency",
+	"UserAddressTypes",
+	"UserBlockDescription",
+	"UserBlockTypes",
+	"UserEmailTypes",
+	"UserGroups",
+	"UserIdentifierTypes",
+	"UserPhoneTypes",
+	"UserPreferredLanguage",
+	"UserRoleStatus",
+	"UserStatCategories",
+	"UserStatisticalTypes",
+	"UserUserType",
+	"UserWebAddressTypes",
+	"VATType",
+	"VendorReferenceNumberType",
+	"VendorSearchStatusFilter",
+	"WebhookEvents",
+	"WebhooksActionType",
+	"WorkbenchPaymentMethod",
+}
+
+// CodeTableNames returns the names of the code tables configured for the Institution.
+func (c Client) CodeTableNames(ctx context.Context) (names []string, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/conf/code-tables", nil)
+	if err != nil {
+		return names, err
 	}
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(names), "Getting code tables")
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return names, err
+	}
+	list := CodeTableList{}
+	err = xml.Unmarshal(body, &list)
+	if err != nil {
+		return names, fmt.Errorf("unmarshalling code table list XML failed: %w\n%v", err, string(body))
+	}
+	for _, table := range list.CodeTables {
+		names = append(names, table.Name)
+	}
+	if len(names) == 0 {
+		return names, fmt.Errorf("no code tables were listed")
+	}
+	return names, nil
+}
+
+// CodeTables returns the code tables with the given names.
+// The names of tables which were not found, like retired tables, are returned in missing.
+func (c Client) CodeTables(ctx context.Context, names []string) (tables []CodeTable, missing []string, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(names), "Getting code tables")
 	defer cancel()
 	for _, name := range names {
 		name := name // avoid closure refering to wrong value
 		jobs <- func() {
 			table, err := c.CodeTable(ctx, name)
-			if err != nil {
+			if IsNotFound(err) {
+				em.Lock()
+				defer em.Unlock()
+				missing = append(missing, name)
+			} else if err != nil {
 				var over *ThresholdReachedError
 				if errors.As(err, &over) {
 					// We've reached the threshold, cancel the context.
@@ -228,12 +279,12 @@ func (c Client) CodeTables(ctx context.Context) (tables []CodeTable, errs []erro
 		_ = bar.Add(1)
 	}
 	StopConcurrent(jobs, wg)
-	return tables, errs
+	return tables, missing, errs
 }
 
 // CodeTable returns a code table.
 func (c Client) CodeTable(ctx context.Context, name string) (table CodeTable, err error) {
-	r, err := http.NewRequest("GET", "/almaws/v1/conf/code-tables/"+name, nil)
+	r, err := http.NewRequest("GET", "/almaws/v1/conf/code-tables/"+url.PathEscape(name), nil)
 	if err != nil {
 		return table, err
 	}
@@ -247,3 +298,25 @@ func (c Client) CodeTable(ctx context.Context, name string) (table CodeTable, er
 	}
 	return table, nil
 }
+
+// CodeTableUpdate PUTs the code table back to the API.
+func (c Client) CodeTableUpdate(ctx context.Context, table CodeTable) (updated CodeTable, err error) {
+	tableBytes, err := xml.Marshal(table)
+	if err != nil {
+		return updated, fmt.Errorf("marshalling code table XML failed: %w", err)
+	}
+	r, err := http.NewRequest("PUT", "/almaws/v1/conf/code-tables/"+url.PathEscape(table.Name), bytes.NewReader(tableBytes))
+	if err != nil {
+		return updated, err
+	}
+	r.Header.Add("Content-Type", "application/xml")
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return updated, err
+	}
+	err = xml.Unmarshal(body, &updated)
+	if err != nil {
+		return updated, fmt.Errorf("unmarshalling code table XML failed: %w\n%v", err, string(body))
+	}
+	return updated, nil
+}
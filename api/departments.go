@@ -16,27 +16,27 @@ import (
 // Department stores data about a location within a library or institution where a service is performed.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_department.xsd/
 type Department struct {
-	Code string `xml:"code"`
-	Name string `xml:"name"`
+	Code string `xml:"code" json:"code"`
+	Name string `xml:"name" json:"name"`
 	Type struct {
-		Text string `xml:",chardata"`
-		Desc string `xml:"desc,attr"`
-	} `xml:"type"`
-	WorkDays string `xml:"work_days"`
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"type" json:"type"`
+	WorkDays string `xml:"work_days" json:"work_days"`
 	Printer  struct {
-		Text string `xml:",chardata"`
-		Desc string `xml:"desc,attr"`
-	} `xml:"printer"`
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"printer" json:"printer"`
 	Owner struct {
-		Text string `xml:",chardata"`
-		Desc string `xml:"desc,attr"`
-	} `xml:"owner"`
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"owner" json:"owner"`
 	ServedLibraries struct {
 		Library []struct {
-			Text string `xml:",chardata"`
-			Desc string `xml:"desc,attr"`
-		} `xml:"library"`
-	} `xml:"served_libraries"`
+			Text string `xml:",chardata" json:"value"`
+			Desc string `xml:"desc,attr" json:"desc"`
+		} `xml:"library" json:"library"`
+	} `xml:"served_libraries" json:"served_libraries"`
 	// Contact Info is TODO
 	//ContactInfo struct {
 	//	Addresses string `xml:"addresses"`
@@ -45,23 +45,23 @@ type Department struct {
 	//} `xml:"contact_info"`
 	Operators struct {
 		Operator []struct {
-			Text      string `xml:",chardata"`
-			Link      string `xml:"link,attr"`
-			PrimaryID string `xml:"primary_id"`
-			FullName  string `xml:"full_name"`
-		} `xml:"operator"`
-	} `xml:"operators"`
-	Description string `xml:"description"`
+			Text      string `xml:",chardata" json:"-"`
+			Link      string `xml:"link,attr" json:"link"`
+			PrimaryID string `xml:"primary_id" json:"primary_id"`
+			FullName  string `xml:"full_name" json:"full_name"`
+		} `xml:"operator" json:"operator"`
+	} `xml:"operators" json:"operators"`
+	Description string `xml:"description" json:"description"`
 }
 
 // Departments stores data for all Departments configured for the Institution.
 // This is a little different than []Department for XML unmarshalling.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_departments.xsd/
 type Departments struct {
-	XMLName          xml.Name     `xml:"departments"`
-	Text             string       `xml:",chardata"`
-	TotalRecordCount string       `xml:"total_record_count,attr"`
-	Departments      []Department `xml:"department"`
+	XMLName          xml.Name     `xml:"departments" json:"-"`
+	Text             string       `xml:",chardata" json:"-"`
+	TotalRecordCount int          `xml:"total_record_count,attr" json:"total_record_count"`
+	Departments      []Department `xml:"department" json:"department"`
 }
 
 // Departments returns the Departments configured for the Institution.
@@ -80,3 +80,13 @@ func (c Client) Departments(ctx context.Context) (departments Departments, err e
 	}
 	return departments, nil
 }
+
+// Department returns the department with the given code, and whether it was found.
+func (d Departments) Department(code string) (department Department, found bool) {
+	for _, department := range d.Departments {
+		if department.Code == code {
+			return department, true
+		}
+	}
+	return department, false
+}
@@ -0,0 +1,328 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// The availability codes of electronic collections, services, and portfolios.
+const (
+	Available    = "11"
+	NotAvailable = "10"
+)
+
+// ElectronicCollection stores data about an electronic collection, which is read and updated as JSON.
+// Fields which are not in the struct are kept in Raw, and are sent back unchanged when the collection is updated.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_electronic_collection.xsd/
+type ElectronicCollection struct {
+	ID                  string `json:"id,omitempty"`
+	PublicName          string `json:"public_name,omitempty"`
+	Type                Value  `json:"type"`
+	Description         string `json:"description,omitempty"`
+	InternalDescription string `json:"internal_description,omitempty"`
+	PublicNote          string `json:"public_note,omitempty"`
+	URLOverride         string `json:"url_override,omitempty"`
+	// Raw is the JSON of the collection returned by the API.
+	Raw []byte `json:"-"`
+}
+
+// Path returns the API path of the collection.
+func (e ElectronicCollection) Path() string {
+	return "/almaws/v1/electronic/e-collections/" + url.PathEscape(e.ID)
+}
+
+// ElectronicService stores data about a service of an electronic collection, which is read and updated as JSON.
+// Fields which are not in the struct are kept in Raw, and are sent back unchanged when the service is updated.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_electronic_service.xsd/
+type ElectronicService struct {
+	ID               string `json:"id,omitempty"`
+	Type             Value  `json:"type"`
+	Description      string `json:"description,omitempty"`
+	ActivationStatus Value  `json:"activation_status"`
+	PublicNote       string `json:"public_note,omitempty"`
+	// CollectionID is the ID of the collection the service belongs to.
+	CollectionID string `json:"-"`
+	// Raw is the JSON of the service returned by the API.
+	Raw []byte `json:"-"`
+}
+
+// Path returns the API path of the service.
+func (e ElectronicService) Path() string {
+	return "/almaws/v1/electronic/e-collections/" + url.PathEscape(e.CollectionID) + "/e-services/" + url.PathEscape(e.ID)
+}
+
+// DateCoverage is a range of dates covered by a portfolio. Empty until fields mean the coverage is open ended.
+type DateCoverage struct {
+	FromYear   Decimal `json:"from_year,omitempty"`
+	FromMonth  Decimal `json:"from_month,omitempty"`
+	FromDay    Decimal `json:"from_day,omitempty"`
+	UntilYear  Decimal `json:"until_year,omitempty"`
+	UntilMonth Decimal `json:"until_month,omitempty"`
+	UntilDay   Decimal `json:"until_day,omitempty"`
+}
+
+// Portfolio stores data about an electronic portfolio, which is read and updated as JSON.
+// Fields which are not in the struct are kept in Raw, and are sent back unchanged when the portfolio is updated.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_portfolio.xsd/
+type Portfolio struct {
+	ID               string `json:"id,omitempty"`
+	ResourceMetadata struct {
+		MMSID Value  `json:"mms_id"`
+		Title string `json:"title,omitempty"`
+	} `json:"resource_metadata"`
+	ElectronicCollection struct {
+		ID      Value `json:"id"`
+		Service Value `json:"service"`
+	} `json:"electronic_collection"`
+	Availability    Value `json:"availability"`
+	CoverageDetails struct {
+		CoverageInUse Value `json:"coverage_in_use"`
+		// LocalDateCoverage is a pointer so it is only sent when it is changed.
+		LocalDateCoverage *[]DateCoverage `json:"local_date_coverage_parameters,omitempty"`
+	} `json:"coverage_details"`
+	LinkingDetails struct {
+		URL               string `json:"url,omitempty"`
+		URLType           Value  `json:"url_type"`
+		StaticURL         string `json:"static_url,omitempty"`
+		StaticURLOverride string `json:"static_url_override,omitempty"`
+	} `json:"linking_details"`
+	PublicNote string `json:"public_note,omitempty"`
+	// Link is the API path or URL of the portfolio.
+	Link string `json:"-"`
+	// Raw is the JSON of the portfolio returned by the API.
+	Raw []byte `json:"-"`
+}
+
+// ElectronicCollection returns the electronic collection with the ID.
+func (c Client) ElectronicCollection(ctx context.Context, ID string) (collection ElectronicCollection, err error) {
+	body, err := c.Call(ctx, "GET", ElectronicCollection{ID: ID}.Path(), JSON, nil, &collection)
+	if err != nil {
+		return collection, err
+	}
+	collection.Raw = body
+	return collection, nil
+}
+
+// ElectronicCollectionUpdate PUTs the electronic collection back to the API.
+// If the collection has Raw JSON, the fields of the struct are merged into it, so fields not in the struct are kept.
+func (c Client) ElectronicCollectionUpdate(ctx context.Context, collection ElectronicCollection) (updated ElectronicCollection, err error) {
+	var in interface{} = collection
+	if len(collection.Raw) != 0 {
+		in, err = mergedJSON(collection.Raw, collection)
+		if err != nil {
+			return updated, err
+		}
+	}
+	body, err := c.Call(ctx, "PUT", collection.Path(), JSON, in, &updated)
+	if err != nil {
+		return updated, err
+	}
+	updated.Raw = body
+	return updated, nil
+}
+
+// ElectronicCollectionDelete deletes the electronic collection.
+func (c Client) ElectronicCollectionDelete(ctx context.Context, collection ElectronicCollection) (err error) {
+	_, err = c.Call(ctx, "DELETE", collection.Path(), JSON, nil, nil)
+	return err
+}
+
+// ElectronicService returns the service with the ID of the electronic collection with the collection ID.
+func (c Client) ElectronicService(ctx context.Context, collectionID, ID string) (service ElectronicService, err error) {
+	service.CollectionID, service.ID = collectionID, ID
+	body, err := c.Call(ctx, "GET", service.Path(), JSON, nil, &service)
+	if err != nil {
+		return service, err
+	}
+	service.Raw = body
+	return service, nil
+}
+
+// ElectronicServiceUpdate PUTs the service back to the API.
+// If the service has Raw JSON, the fields of the struct are merged into it, so fields not in the struct are kept.
+func (c Client) ElectronicServiceUpdate(ctx context.Context, service ElectronicService) (updated ElectronicService, err error) {
+	var in interface{} = service
+	if len(service.Raw) != 0 {
+		in, err = mergedJSON(service.Raw, service)
+		if err != nil {
+			return updated, err
+		}
+	}
+	body, err := c.Call(ctx, "PUT", service.Path(), JSON, in, &updated)
+	if err != nil {
+		return updated, err
+	}
+	updated.Raw = body
+	updated.CollectionID = service.CollectionID
+	return updated, nil
+}
+
+// ElectronicServiceDelete deletes the service.
+func (c Client) ElectronicServiceDelete(ctx context.Context, service ElectronicService) (err error) {
+	_, err = c.Call(ctx, "DELETE", service.Path(), JSON, nil, nil)
+	return err
+}
+
+// PortfolioMembersPortfolios returns the portfolios refered to by portfolio members. The members must be from a set with content PORTFOLIO.
+func (c Client) PortfolioMembersPortfolios(ctx context.Context, members []Member) (portfolios []Portfolio, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(members), "Getting portfolios")
+	defer cancel()
+	for _, member := range members {
+		member := member // avoid closure refering to wrong value
+		jobs <- func() {
+			portfolio, err := c.Portfolio(ctx, member.Link)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				portfolios = append(portfolios, portfolio)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return portfolios, errs
+}
+
+// Portfolio returns the portfolio with the link, like the link of a set member.
+func (c Client) Portfolio(ctx context.Context, link string) (portfolio Portfolio, err error) {
+	body, err := c.Call(ctx, "GET", link, JSON, nil, &portfolio)
+	if err != nil {
+		return portfolio, err
+	}
+	portfolio.Raw = body
+	portfolio.Link = link
+	return portfolio, nil
+}
+
+// PortfoliosUpdate PUTs the portfolios back to the API.
+func (c Client) PortfoliosUpdate(ctx context.Context, portfolios []Portfolio) (updatedPortfolios []Portfolio, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(portfolios), "Updating portfolios")
+	defer cancel()
+	for _, portfolio := range portfolios {
+		portfolio := portfolio // avoid closure refering to wrong value
+		jobs <- func() {
+			updated, err := c.PortfolioUpdate(ctx, portfolio)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				updatedPortfolios = append(updatedPortfolios, updated)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return updatedPortfolios, errs
+}
+
+// PortfolioUpdate PUTs the portfolio back to the API, using the portfolio's Link.
+// If the portfolio has Raw JSON, the fields of the struct are merged into it, so fields not in the struct are kept.
+// A changed local date coverage replaces the coverage in Raw instead of being merged into it.
+func (c Client) PortfolioUpdate(ctx context.Context, portfolio Portfolio) (updated Portfolio, err error) {
+	if portfolio.Link == "" {
+		return updated, fmt.Errorf("the portfolio with ID '%v' has no link to update", portfolio.ID)
+	}
+	var in interface{} = portfolio
+	if len(portfolio.Raw) != 0 {
+		merged, err := mergedJSON(portfolio.Raw, portfolio)
+		if err != nil {
+			return updated, err
+		}
+		// The local date coverage replaces the live coverage, so the date parts which were left out are cleared.
+		if portfolio.CoverageDetails.LocalDateCoverage != nil {
+			merged, err = replacedJSON(merged, portfolio.CoverageDetails.LocalDateCoverage, "coverage_details", "local_date_coverage_parameters")
+			if err != nil {
+				return updated, err
+			}
+		}
+		in = merged
+	}
+	body, err := c.Call(ctx, "PUT", portfolio.Link, JSON, in, &updated)
+	if err != nil {
+		return updated, err
+	}
+	updated.Raw = body
+	updated.Link = portfolio.Link
+	return updated, nil
+}
+
+// PortfoliosRestore PUTs the Raw JSON of the portfolios, like portfolios read from a backup, to their links unchanged.
+func (c Client) PortfoliosRestore(ctx context.Context, portfolios []Portfolio) (restoredPortfolios []Portfolio, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(portfolios), "Restoring portfolios")
+	defer cancel()
+	for _, portfolio := range portfolios {
+		portfolio := portfolio // avoid closure refering to wrong value
+		jobs <- func() {
+			restored, err := c.PortfolioRestore(ctx, portfolio)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				restoredPortfolios = append(restoredPortfolios, restored)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return restoredPortfolios, errs
+}
+
+// PortfolioRestore PUTs the Raw JSON of the portfolio to its Link unchanged.
+func (c Client) PortfolioRestore(ctx context.Context, portfolio Portfolio) (restored Portfolio, err error) {
+	if portfolio.Link == "" || len(portfolio.Raw) == 0 {
+		return restored, fmt.Errorf("the portfolio with ID '%v' has no link or JSON to restore", portfolio.ID)
+	}
+	body, err := c.Call(ctx, "PUT", portfolio.Link, JSON, json.RawMessage(portfolio.Raw), &restored)
+	if err != nil {
+		return restored, err
+	}
+	restored.Raw = body
+	restored.Link = portfolio.Link
+	return restored, nil
+}
+
+// PortfolioDelete deletes the portfolio, using the portfolio's Link.
+func (c Client) PortfolioDelete(ctx context.Context, portfolio Portfolio) (err error) {
+	if portfolio.Link == "" {
+		return fmt.Errorf("the portfolio with ID '%v' has no link to delete", portfolio.ID)
+	}
+	_, err = c.Call(ctx, "DELETE", portfolio.Link, JSON, nil, nil)
+	return err
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Format is the format of the body of a request or response.
+// Alma supports JSON for nearly all endpoints, but records which hold MARC,
+// like bibs and holdings, should be sent and received as XML.
+// Types with json tags can be used with either format.
+// https://developers.exlibrisgroup.com/alma/apis/#format
+type Format int
+
+// The formats supported by the Alma API.
+const (
+	XML Format = iota
+	JSON
+)
+
+func (f Format) String() string {
+	if f == JSON {
+		return "JSON"
+	}
+	return "XML"
+}
+
+// ContentType returns the media type of the format, used in Content-Type and Accept headers.
+func (f Format) ContentType() string {
+	if f == JSON {
+		return "application/json"
+	}
+	return "application/xml"
+}
+
+// Marshal returns the encoding of v in the format.
+func (f Format) Marshal(v interface{}) ([]byte, error) {
+	if f == JSON {
+		return json.Marshal(v)
+	}
+	return xml.Marshal(v)
+}
+
+// Unmarshal parses the data in the format and stores the result in the value pointed to by v.
+func (f Format) Unmarshal(data []byte, v interface{}) error {
+	if f == JSON {
+		return json.Unmarshal(data, v)
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// Call makes a request to the path using the format.
+// If in is not nil, it is marshalled and sent as the body of the request.
+// If out is not nil, the body of the response is unmarshalled into it.
+// The body of the response is returned.
+func (c *Client) Call(ctx context.Context, method, path string, format Format, in, out interface{}) (body []byte, err error) {
+	var r *http.Request
+	if in != nil {
+		inBytes, err := format.Marshal(in)
+		if err != nil {
+			return body, fmt.Errorf("marshalling %v for %v %v failed: %w", format, method, path, err)
+		}
+		r, err = http.NewRequest(method, path, bytes.NewReader(inBytes))
+		if err != nil {
+			return body, err
+		}
+		r.Header.Add("Content-Type", format.ContentType())
+	} else {
+		r, err = http.NewRequest(method, path, nil)
+		if err != nil {
+			return body, err
+		}
+	}
+	r.Header.Add("Accept", format.ContentType())
+	body, err = c.Do(ctx, r)
+	if err != nil {
+		return body, err
+	}
+	if out != nil {
+		err = format.Unmarshal(body, out)
+		if err != nil {
+			return body, fmt.Errorf("unmarshalling %v from %v %v failed: %w\n%v", format, method, path, err, string(body))
+		}
+	}
+	return body, nil
+}
+
+// Value is a code and its description, which Alma sends in JSON as {"value": "CODE", "desc": "Description"}.
+type Value struct {
+	Value string `json:"value,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// Label returns the description of the value, or the code if it has no description.
+func (v Value) Label() string {
+	if v.Desc != "" {
+		return v.Desc
+	}
+	return v.Value
+}
+
+// Decimal is a number which Alma sends in JSON as either a number or a string, like an amount of money.
+// It is kept as text so it isn't rounded.
+type Decimal string
+
+// UnmarshalJSON reads a number, a string, or null.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = ""
+		return nil
+	}
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err == nil {
+		*d = Decimal(s)
+		return nil
+	}
+	var n json.Number
+	err = json.Unmarshal(data, &n)
+	if err != nil {
+		return fmt.Errorf("%s is not a number", data)
+	}
+	*d = Decimal(n)
+	return nil
+}
+
+// MarshalJSON writes the decimal as a number, or null if it is empty.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	_, err := strconv.ParseFloat(string(d), 64)
+	if err != nil {
+		return nil, fmt.Errorf("'%v' is not a number", string(d))
+	}
+	return []byte(d), nil
+}
+
+// Float returns the decimal as a float64, or zero if it is empty or not a number.
+func (d Decimal) Float() float64 {
+	f, _ := strconv.ParseFloat(string(d), 64)
+	return f
+}
+
+// listPages gets the pages of a list with total records which follow the first page, LimitParam records at a time.
+// The get function is called concurrently with the offset of each page, so it must be safe for concurrent use.
+func (c Client) listPages(ctx context.Context, total int, desc string, get func(ctx context.Context, offset int) error) (errs []error) {
+	pages := total / LimitParam
+	if total%LimitParam != 0 {
+		pages++
+	}
+	if pages <= 1 {
+		return errs
+	}
+	ctx, cancel, em, _, jobs, wg, bar := c.StartConcurrent(ctx, pages-1, desc)
+	defer cancel()
+	for i := 1; i < pages; i++ {
+		offset := i * LimitParam
+		jobs <- func() {
+			err := get(ctx, offset)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return errs
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// Fund stores data about a fund or ledger, which is read as JSON.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_fund.xsd/
+type Fund struct {
+	ID                string `json:"id,omitempty"`
+	Code              string `json:"code,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Type              Value  `json:"type"`
+	Status            Value  `json:"status"`
+	FiscalPeriod      Value  `json:"fiscal_period"`
+	Ledger            Value  `json:"ledger"`
+	ParentFund        Value  `json:"parent_fund"`
+	Currency          Value  `json:"currency"`
+	AllocatedBalance  Amount `json:"allocated_balance"`
+	EncumberedBalance Amount `json:"encumbered_balance"`
+	ExpendedBalance   Amount `json:"expended_balance"`
+	AvailableBalance  Amount `json:"available_balance"`
+	CashBalance       Amount `json:"cash_balance"`
+}
+
+// Funds stores a page of funds.
+type Funds struct {
+	Funds            []Fund `json:"fund"`
+	TotalRecordCount int    `json:"total_record_count"`
+}
+
+// FundsQuery stores the parameters used to list funds.
+type FundsQuery struct {
+	// Status is ACTIVE, INACTIVE, or ALL.
+	Status string
+	// FiscalPeriod is the fiscal period, like 2021. The current fiscal period is used if it is empty.
+	FiscalPeriod string
+	// EntityType is FUND, LEDGER, SUMMARY, or ALL.
+	EntityType string
+}
+
+// query adds the parameters to the query. The full view includes the balances of the funds.
+func (f FundsQuery) query(q url.Values) {
+	q.Set("mode", "ALL")
+	q.Set("view", "full")
+	if f.Status != "" {
+		q.Set("status", f.Status)
+	}
+	if f.FiscalPeriod != "" {
+		q.Set("fiscal_period", f.FiscalPeriod)
+	}
+	if f.EntityType != "" {
+		q.Set("entity_type", f.EntityType)
+	}
+}
+
+// Funds returns the funds which match the query.
+func (c Client) Funds(ctx context.Context, query FundsQuery) (funds []Fund, errs []error) {
+	get := func(ctx context.Context, offset int) (page Funds, err error) {
+		q := url.Values{}
+		query.query(q)
+		q.Set("limit", strconv.Itoa(LimitParam))
+		q.Set("offset", strconv.Itoa(offset))
+		_, err = c.Call(ctx, "GET", "/almaws/v1/acq/funds?"+q.Encode(), JSON, nil, &page)
+		return page, err
+	}
+	first, err := get(ctx, 0)
+	if err != nil {
+		return funds, []error{err}
+	}
+	funds = append(funds, first.Funds...)
+	om := &sync.Mutex{}
+	errs = c.listPages(ctx, first.TotalRecordCount, "Getting funds", func(ctx context.Context, offset int) error {
+		page, err := get(ctx, offset)
+		if err != nil {
+			return err
+		}
+		om.Lock()
+		defer om.Unlock()
+		funds = append(funds, page.Funds...)
+		return nil
+	})
+	return funds, errs
+}
+
+// Fund returns the fund with the ID.
+func (c Client) Fund(ctx context.Context, ID string) (fund Fund, err error) {
+	_, err = c.Call(ctx, "GET", "/almaws/v1/acq/funds/"+url.PathEscape(ID), JSON, nil, &fund)
+	return fund, err
+}
@@ -83,6 +83,9 @@ type Holding struct {
 	} `xml:"record"`
 	// HoldingListMember is an optional field for the 'originating' holding list member.
 	HoldingListMember HoldingListMember `xml:"-"`
+	// Raw is the XML of the holding returned by the API.
+	// It is patched when the holding is updated, so elements not in the struct are kept.
+	Raw []byte `xml:"-"`
 }
 
 // EightFiftyTwoSubHSubI returns the h and i parts of the 852, seperated with a space.
@@ -106,7 +109,7 @@ func (h Holding) EightFiftyTwoSubHSubI() (callnumber string) {
 
 // BibMembersHoldingListMembers returns the holding list members for the members. The members must be from a set with content BIB_MMS.
 func (c Client) BibMembersHoldingListMembers(ctx context.Context, members []Member) (holdingListMembers []HoldingListMember, errs []error) {
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(members), "Getting holding list members")
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(members), "Getting holding list members")
 	defer cancel()
 	for _, member := range members {
 		member := member // avoid closure refering to wrong value
@@ -158,7 +161,7 @@ func (c Client) BibMemberHoldingListMembers(ctx context.Context, member Member)
 
 // HoldingListMembersToHoldings returns the holdings records refered to by a slice of holdings list members.
 func (c Client) HoldingListMembersToHoldings(ctx context.Context, holdingListMembers []HoldingListMember) (holdings []Holding, errs []error) {
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(holdingListMembers), "Getting holdings records")
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(holdingListMembers), "Getting holdings records")
 	defer cancel()
 	for _, holdingListMember := range holdingListMembers {
 		holdingListMember := holdingListMember // avoid closure refering to wrong value
@@ -204,6 +207,7 @@ func (c Client) HoldingListMemberToHolding(ctx context.Context, holdingListMembe
 	if err != nil {
 		return holding, fmt.Errorf("unmarshalling holding XML failed: %w\n%v", err, string(body))
 	}
+	holding.Raw = body
 	// Enrich the API record with the originating holding list member.
 	holding.HoldingListMember = holdingListMember
 	return holding, nil
@@ -211,7 +215,7 @@ func (c Client) HoldingListMemberToHolding(ctx context.Context, holdingListMembe
 
 // HoldingsUpdate PUTs the holdings back to the API.
 func (c Client) HoldingsUpdate(ctx context.Context, holdings []Holding) (updatedHoldings []Holding, errs []error) {
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(holdings), "Updating holdings records")
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(holdings), "Updating holdings records")
 	defer cancel()
 	for _, holding := range holdings {
 		holding := holding // avoid closure refering to wrong value
@@ -240,6 +244,7 @@ func (c Client) HoldingsUpdate(ctx context.Context, holdings []Holding) (updated
 }
 
 // HoldingUpdate PUTs the holding back to the API.
+// If the holding has Raw XML, only the elements which changed are replaced in it.
 func (c Client) HoldingUpdate(ctx context.Context, holding Holding) (updated Holding, err error) {
 	url, err := url.Parse(holding.HoldingListMember.Link)
 	if err != nil {
@@ -249,6 +254,12 @@ func (c Client) HoldingUpdate(ctx context.Context, holding Holding) (updated Hol
 	if err != nil {
 		return updated, fmt.Errorf("marshalling holding XML failed: %w", err)
 	}
+	if len(holding.Raw) != 0 {
+		holdingBytes, err = patchXML(holding.Raw, holdingBytes)
+		if err != nil {
+			return updated, fmt.Errorf("patching holding XML failed: %w", err)
+		}
+	}
 	r, err := http.NewRequest("PUT", url.String(), bytes.NewReader(holdingBytes))
 	if err != nil {
 		return updated, err
@@ -262,6 +273,7 @@ func (c Client) HoldingUpdate(ctx context.Context, holding Holding) (updated Hol
 	if err != nil {
 		return updated, fmt.Errorf("unmarshalling holding XML failed: %w\n%v", err, string(body))
 	}
+	updated.Raw = body
 	// Enrich the API record with the originating holding list member.
 	updated.HoldingListMember = holding.HoldingListMember
 	return updated, nil
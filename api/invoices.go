@@ -0,0 +1,83 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// DefaultPaymentMethod is the payment method of invoices paid through the accounting department.
+const DefaultPaymentMethod = "ACCOUNTINGDEPARTMENT"
+
+// Invoice stores data about an invoice, which is read and created as JSON.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_invoice.xsd/
+type Invoice struct {
+	ID                    string  `json:"id,omitempty"`
+	Number                string  `json:"number,omitempty"`
+	InvoiceDate           string  `json:"invoice_date,omitempty"`
+	Vendor                Value   `json:"vendor"`
+	VendorAccount         string  `json:"vendor_account,omitempty"`
+	TotalAmount           Decimal `json:"total_amount,omitempty"`
+	Currency              Value   `json:"currency"`
+	Owner                 *Value  `json:"owner,omitempty"`
+	PaymentMethod         Value   `json:"payment_method"`
+	ReferenceNumber       string  `json:"reference_number,omitempty"`
+	InvoiceStatus         Value   `json:"invoice_status"`
+	InvoiceWorkflowStatus Value   `json:"invoice_workflow_status"`
+	InvoiceApprovalStatus Value   `json:"invoice_approval_status"`
+}
+
+// InvoiceLine stores data about a line of an invoice, which is read and created as JSON.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_invoice_line.xsd/
+type InvoiceLine struct {
+	ID               string             `json:"id,omitempty"`
+	Number           string             `json:"number,omitempty"`
+	Type             Value              `json:"type"`
+	POLine           string             `json:"po_line,omitempty"`
+	Price            Decimal            `json:"price,omitempty"`
+	Quantity         int                `json:"quantity,omitempty"`
+	TotalPrice       Decimal            `json:"total_price,omitempty"`
+	Note             string             `json:"note,omitempty"`
+	FundDistribution []FundDistribution `json:"fund_distribution,omitempty"`
+}
+
+// Invoice returns the invoice with the ID.
+func (c Client) Invoice(ctx context.Context, ID string) (invoice Invoice, err error) {
+	_, err = c.Call(ctx, "GET", "/almaws/v1/acq/invoices/"+url.PathEscape(ID), JSON, nil, &invoice)
+	return invoice, err
+}
+
+// InvoiceCreate POSTs a new invoice to the API, and returns the invoice Alma created.
+func (c Client) InvoiceCreate(ctx context.Context, invoice Invoice) (created Invoice, err error) {
+	_, err = c.Call(ctx, "POST", "/almaws/v1/acq/invoices", JSON, invoice, &created)
+	if err != nil {
+		return created, fmt.Errorf("creating invoice %v failed: %w", invoice.Number, err)
+	}
+	return created, nil
+}
+
+// InvoiceLineCreate POSTs a new line to the invoice with the ID, and returns the line Alma created.
+func (c Client) InvoiceLineCreate(ctx context.Context, ID string, line InvoiceLine) (created InvoiceLine, err error) {
+	_, err = c.Call(ctx, "POST", "/almaws/v1/acq/invoices/"+url.PathEscape(ID)+"/lines", JSON, line, &created)
+	if err != nil {
+		return created, fmt.Errorf("creating the line for PO line %v on invoice %v failed: %w", line.POLine, ID, err)
+	}
+	return created, nil
+}
+
+// InvoiceProcess POSTs the process_invoice operation, which approves the invoice with the ID
+// or sends it to review, depending on the approval rules in Alma.
+func (c Client) InvoiceProcess(ctx context.Context, ID string) (processed Invoice, err error) {
+	path := "/almaws/v1/acq/invoices/" + url.PathEscape(ID) + "?op=process_invoice"
+	_, err = c.Call(ctx, "POST", path, JSON, struct{}{}, &processed)
+	if err != nil {
+		return processed, fmt.Errorf("processing invoice %v failed: %w", ID, err)
+	}
+	return processed, nil
+}
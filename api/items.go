@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
 // DefaultCircDesk is the default circulation desk code used when scanning an item in.
@@ -25,20 +26,235 @@ type Item struct {
 	MMSID      string   `xml:"bib_data>mms_id"`
 	Title      string   `xml:"bib_data>title"`
 	Author     string   `xml:"bib_data>author"`
+	HoldingID  string   `xml:"holding_data>holding_id"`
 	CallNumber string   `xml:"holding_data>call_number"`
+	PID        string   `xml:"item_data>pid"`
 	Barcode    string   `xml:"item_data>barcode"`
+	POLine     string   `xml:"item_data>po_line"`
+	Library    struct {
+		Text string `xml:",chardata"`
+		Desc string `xml:"desc,attr"`
+	} `xml:"item_data>library"`
+	Location struct {
+		Text string `xml:",chardata"`
+		Desc string `xml:"desc,attr"`
+	} `xml:"item_data>location"`
+	ProcessType struct {
+		Text string `xml:",chardata"`
+		Desc string `xml:"desc,attr"`
+	} `xml:"item_data>process_type"`
+	WorkOrderType struct {
+		Text string `xml:",chardata"`
+		Desc string `xml:"desc,attr"`
+	} `xml:"item_data>work_order_type"`
+	WorkOrderAt struct {
+		Text string `xml:",chardata"`
+		Desc string `xml:"desc,attr"`
+	} `xml:"item_data>work_order_at"`
 	// An optional attribute for the link to the item.
 	Link string `xml:"-"`
 }
 
+// ScanInOptions stores the parameters of a scan in operation.
+type ScanInOptions struct {
+	// CircDesk is the circulation desk code. It is used when Department is empty.
+	CircDesk string
+	// Library is the library code of the circulation desk or department.
+	Library string
+	// Department is the department code, for scanning items in at a work order department.
+	Department string
+	// WorkOrderType is the work order type code, used with Department.
+	WorkOrderType string
+	// Status is the work order status code the item moves to, used with Department.
+	Status string
+	// Done marks the work order step as complete.
+	Done bool
+	// Confirm skips Alma's confirmation of scan in operations which would otherwise fail.
+	Confirm bool
+}
+
+// query sets the scan in parameters on the query.
+func (o ScanInOptions) query(q url.Values) {
+	q.Set("op", "scan")
+	q.Set("register_in_house_use", "false")
+	if o.Department != "" {
+		q.Set("department", o.Department)
+		if o.WorkOrderType != "" {
+			q.Set("work_order_type", o.WorkOrderType)
+		}
+		if o.Status != "" {
+			q.Set("status", o.Status)
+		}
+		q.Set("done", strconv.FormatBool(o.Done))
+	} else {
+		q.Set("circ_desk", o.CircDesk)
+	}
+	if o.Library != "" {
+		q.Set("library", o.Library)
+	}
+	q.Set("confirm", strconv.FormatBool(o.Confirm))
+}
+
+// ItemMembersItems returns the items refered to by item members. The members must be from a set with content ITEM.
+func (c Client) ItemMembersItems(ctx context.Context, members []Member) (items []Item, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(members), "Getting items")
+	defer cancel()
+	for _, member := range members {
+		member := member // avoid closure refering to wrong value
+		jobs <- func() {
+			item, err := c.ItemMemberItem(ctx, member)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				items = append(items, item)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return items, errs
+}
+
+// ItemMemberItem returns the item refered to by an item member.
+func (c Client) ItemMemberItem(ctx context.Context, member Member) (item Item, err error) {
+	r, err := http.NewRequest("GET", member.Link, nil)
+	if err != nil {
+		return item, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return item, err
+	}
+	err = xml.Unmarshal(body, &item)
+	if err != nil {
+		return item, fmt.Errorf("unmarshalling item XML failed: %w\n%v", err, string(body))
+	}
+	item.Link = member.Link
+	return item, nil
+}
+
+// ItemsFromBarcodes returns the items with the barcodes.
+func (c Client) ItemsFromBarcodes(ctx context.Context, barcodes []string) (items []Item, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(barcodes), "Getting items by barcode")
+	defer cancel()
+	for _, barcode := range barcodes {
+		barcode := barcode // avoid closure refering to wrong value
+		jobs <- func() {
+			item, err := c.ItemFromBarcode(ctx, barcode)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				items = append(items, item)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return items, errs
+}
+
+// ItemFromBarcode returns the item with the barcode.
+func (c Client) ItemFromBarcode(ctx context.Context, barcode string) (item Item, err error) {
+	q := url.Values{}
+	q.Set("item_barcode", barcode)
+	r, err := http.NewRequest("GET", "/almaws/v1/items?"+q.Encode(), nil)
+	if err != nil {
+		return item, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return item, fmt.Errorf("getting the item with barcode '%v' failed: %w", barcode, err)
+	}
+	err = xml.Unmarshal(body, &item)
+	if err != nil {
+		return item, fmt.Errorf("unmarshalling item XML failed: %w\n%v", err, string(body))
+	}
+	item.Link = item.Path()
+	return item, nil
+}
+
+// ItemsFromPIDs returns the items with the PIDs.
+func (c Client) ItemsFromPIDs(ctx context.Context, PIDs []string) (items []Item, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(PIDs), "Getting items by PID")
+	defer cancel()
+	for _, PID := range PIDs {
+		PID := PID // avoid closure refering to wrong value
+		jobs <- func() {
+			item, err := c.ItemFromPID(ctx, PID)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				items = append(items, item)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return items, errs
+}
+
+// ItemFromPID returns the item with the PID.
+// Alma accepts X in place of the MMS ID and holding ID when the item PID is known.
+func (c Client) ItemFromPID(ctx context.Context, PID string) (item Item, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/bibs/X/holdings/X/items/"+url.PathEscape(PID), nil)
+	if err != nil {
+		return item, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return item, fmt.Errorf("getting the item with PID '%v' failed: %w", PID, err)
+	}
+	err = xml.Unmarshal(body, &item)
+	if err != nil {
+		return item, fmt.Errorf("unmarshalling item XML failed: %w\n%v", err, string(body))
+	}
+	item.Link = item.Path()
+	return item, nil
+}
+
+// Path returns the API path of the item, built from its MMS ID, holding ID, and PID.
+func (i Item) Path() string {
+	return "/almaws/v1/bibs/" + url.PathEscape(i.MMSID) + "/holdings/" + url.PathEscape(i.HoldingID) + "/items/" + url.PathEscape(i.PID)
+}
+
 // ItemMembersScanIn scans members in. The members must be from a set with content ITEM.
-func (c Client) ItemMembersScanIn(ctx context.Context, members []Member, circdesk, library string) (scannedIn []Item, errs []error) {
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(members), "Scanning items in")
+func (c Client) ItemMembersScanIn(ctx context.Context, members []Member, options ScanInOptions) (scannedIn []Item, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(members), "Scanning items in")
 	defer cancel()
 	for _, member := range members {
 		member := member // avoid closure refering to wrong value
 		jobs <- func() {
-			item, err := c.ItemMemberScanIn(ctx, member, circdesk, library)
+			item, err := c.ItemMemberScanIn(ctx, member, options)
 			if err != nil {
 				var over *ThresholdReachedError
 				if errors.As(err, &over) {
@@ -62,16 +278,13 @@ func (c Client) ItemMembersScanIn(ctx context.Context, members []Member, circdes
 }
 
 // ItemMemberScanIn POSTs the scan operation on an item member.
-func (c Client) ItemMemberScanIn(ctx context.Context, member Member, circdesk, library string) (item Item, err error) {
+func (c Client) ItemMemberScanIn(ctx context.Context, member Member, options ScanInOptions) (item Item, err error) {
 	url, err := url.Parse(member.Link)
 	if err != nil {
 		return item, err
 	}
 	q := url.Query()
-	q.Set("op", "scan")
-	q.Set("register_in_house_use", "false")
-	q.Set("circ_desk", circdesk)
-	q.Set("library", library)
+	options.query(q)
 	url.RawQuery = q.Encode()
 	r, err := http.NewRequest("POST", url.String(), nil)
 	if err != nil {
@@ -0,0 +1,359 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultJobPollInterval is the default time to wait between checks on the status of a running job instance.
+const DefaultJobPollInterval = 10 * time.Second
+
+// JobPollFailures is the number of checks on a job instance in a row which can fail before JobInstanceWait stops waiting.
+const JobPollFailures = 5
+
+// The statuses of job instances.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_job_instance.xsd/
+const (
+	JobQueued           = "QUEUED"
+	JobPending          = "PENDING"
+	JobInitializing     = "INITIALIZING"
+	JobRunning          = "RUNNING"
+	JobFinalizing       = "FINALIZING"
+	JobCompletedSuccess = "COMPLETED_SUCCESS"
+	JobCompletedNoBulks = "COMPLETED_NO_BULKS"
+	JobCompletedWarning = "COMPLETED_WARNING"
+	JobCompletedFailed  = "COMPLETED_FAILED"
+	JobFailed           = "FAILED"
+	JobSystemAborted    = "SYSTEM_ABORTED"
+	JobManuallyAborted  = "MANUALLY_ABORTED"
+	JobSkipped          = "SKIPPED"
+)
+
+// JobParameter is a parameter a job is run with, like set_id.
+type JobParameter struct {
+	Name  Value  `json:"name"`
+	Value string `json:"value"`
+}
+
+// Job stores data about a job, which is read as JSON.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_job.xsd/
+type Job struct {
+	ID          string         `json:"id,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Type        Value          `json:"type"`
+	Category    Value          `json:"category"`
+	Content     Value          `json:"content"`
+	Parameters  []JobParameter `json:"parameter,omitempty"`
+	// AdditionalInfo is returned when the job is run, with a link to the new job instance.
+	AdditionalInfo *struct {
+		Value string `json:"value"`
+		Link  string `json:"link"`
+	} `json:"additional_info,omitempty"`
+	Link string `json:"link,omitempty"`
+}
+
+// SetParameter sets the value of the job's parameter with the name, adding the parameter if the job doesn't have it.
+func (j *Job) SetParameter(name, value string) {
+	for i := range j.Parameters {
+		if j.Parameters[i].Name.Value == name {
+			j.Parameters[i].Value = value
+			return
+		}
+	}
+	j.Parameters = append(j.Parameters, JobParameter{Name: Value{Value: name}, Value: value})
+}
+
+// Jobs stores a page of jobs.
+type Jobs struct {
+	Jobs             []Job `json:"job"`
+	TotalRecordCount int   `json:"total_record_count"`
+}
+
+// JobsQuery stores the parameters used to list jobs. Empty values are not sent.
+type JobsQuery struct {
+	// Type is MANUAL, SCHEDULED, or OTHER.
+	Type string
+	// Category is the job category code, like NORMALIZATION or PHYSICAL_INVENTORY.
+	Category string
+	// ProfileID is the ID of an import profile, to find the job which runs the profile.
+	ProfileID string
+}
+
+// JobCounter is a count reported by a job instance, like the number of records processed.
+type JobCounter struct {
+	Type  Value   `json:"type"`
+	Value Decimal `json:"value"`
+}
+
+// JobInstance stores data about a run of a job, which is read as JSON.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_job_instance.xsd/
+type JobInstance struct {
+	ID          string       `json:"id"`
+	ExternalID  string       `json:"external_id,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	Progress    int          `json:"progress"`
+	Status      Value        `json:"status"`
+	SubmittedBy Value        `json:"submitted_by"`
+	SubmitDate  string       `json:"submit_date,omitempty"`
+	StartDate   string       `json:"start_date,omitempty"`
+	EndDate     string       `json:"end_date,omitempty"`
+	Alerts      []Value      `json:"alert,omitempty"`
+	Counters    []JobCounter `json:"counter,omitempty"`
+	JobInfo     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"job_info"`
+	Link string `json:"link,omitempty"`
+}
+
+// Running returns true if the job instance hasn't finished.
+func (i JobInstance) Running() bool {
+	switch i.Status.Value {
+	case JobQueued, JobPending, JobInitializing, JobRunning, JobFinalizing:
+		return true
+	}
+	return false
+}
+
+// Succeeded returns true if the job instance finished without failing.
+// Instances which completed with warnings succeeded, the warnings are in the alerts.
+func (i JobInstance) Succeeded() bool {
+	switch i.Status.Value {
+	case JobCompletedSuccess, JobCompletedNoBulks, JobCompletedWarning:
+		return true
+	}
+	return false
+}
+
+// Jobs returns the jobs which match the query.
+func (c Client) Jobs(ctx context.Context, query JobsQuery) (jobs []Job, errs []error) {
+	get := func(ctx context.Context, offset int) (page Jobs, err error) {
+		q := url.Values{}
+		if query.Type != "" {
+			q.Set("type", query.Type)
+		}
+		if query.Category != "" {
+			q.Set("category", query.Category)
+		}
+		if query.ProfileID != "" {
+			q.Set("profile_id", query.ProfileID)
+		}
+		q.Set("limit", strconv.Itoa(LimitParam))
+		q.Set("offset", strconv.Itoa(offset))
+		_, err = c.Call(ctx, "GET", "/almaws/v1/conf/jobs?"+q.Encode(), JSON, nil, &page)
+		return page, err
+	}
+	first, err := get(ctx, 0)
+	if err != nil {
+		return jobs, []error{err}
+	}
+	jobs = append(jobs, first.Jobs...)
+	om := &sync.Mutex{}
+	errs = c.listPages(ctx, first.TotalRecordCount, "Getting jobs", func(ctx context.Context, offset int) error {
+		page, err := get(ctx, offset)
+		if err != nil {
+			return err
+		}
+		om.Lock()
+		defer om.Unlock()
+		jobs = append(jobs, page.Jobs...)
+		return nil
+	})
+	return jobs, errs
+}
+
+// Job returns the job with the ID, including its parameters.
+func (c Client) Job(ctx context.Context, ID string) (job Job, err error) {
+	_, err = c.Call(ctx, "GET", "/almaws/v1/conf/jobs/"+url.PathEscape(ID), JSON, nil, &job)
+	return job, err
+}
+
+// JobRun submits the manual job to be run with its parameters, and returns the ID of the new job instance.
+func (c Client) JobRun(ctx context.Context, job Job) (instanceID string, err error) {
+	run := Job{}
+	_, err = c.Call(ctx, "POST", "/almaws/v1/conf/jobs/"+url.PathEscape(job.ID)+"?op=run", JSON, job, &run)
+	if err != nil {
+		return instanceID, fmt.Errorf("running job %v failed: %w", job.ID, err)
+	}
+	return run.instanceID()
+}
+
+// JobRunFile submits the import profile's job to be run on the uploaded file, instead of the files on the profile's FTP server,
+// and returns the ID of the new job instance.
+// Alma only accepts uploads for import profiles which are configured for it.
+// The upload isn't described in Alma's documentation of the run operation, which only takes the job,
+// so it hasn't been verified against Alma; the file is sent as a multipart part next to the job.
+func (c Client) JobRunFile(ctx context.Context, job Job, filename string, file []byte) (instanceID string, err error) {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return instanceID, fmt.Errorf("marshalling job %v failed: %w", job.ID, err)
+	}
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="job"`)
+	h.Set("Content-Type", JSON.ContentType())
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return instanceID, err
+	}
+	_, err = pw.Write(jobJSON)
+	if err != nil {
+		return instanceID, err
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return instanceID, err
+	}
+	_, err = fw.Write(file)
+	if err != nil {
+		return instanceID, err
+	}
+	err = mw.Close()
+	if err != nil {
+		return instanceID, err
+	}
+	r, err := http.NewRequest("POST", "/almaws/v1/conf/jobs/"+url.PathEscape(job.ID)+"?op=run", bytes.NewReader(b.Bytes()))
+	if err != nil {
+		return instanceID, err
+	}
+	r.Header.Add("Content-Type", mw.FormDataContentType())
+	r.Header.Add("Accept", JSON.ContentType())
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return instanceID, fmt.Errorf("running job %v on %v failed: %w", job.ID, filename, err)
+	}
+	run := Job{}
+	err = JSON.Unmarshal(body, &run)
+	if err != nil {
+		return instanceID, fmt.Errorf("unmarshalling job JSON failed: %w\n%v", err, string(body))
+	}
+	return run.instanceID()
+}
+
+// instanceID returns the ID of the job instance from the link Alma returns when the job is run.
+func (j Job) instanceID() (ID string, err error) {
+	if j.AdditionalInfo == nil || j.AdditionalInfo.Link == "" {
+		return ID, fmt.Errorf("running job %v did not return a link to the job instance", j.ID)
+	}
+	// The link is like /almaws/v1/conf/jobs/{job ID}/instances/{instance ID}.
+	return path.Base(j.AdditionalInfo.Link), nil
+}
+
+// JobInstance returns the instance of the job, with its status, progress, and counters.
+func (c Client) JobInstance(ctx context.Context, jobID, instanceID string) (instance JobInstance, err error) {
+	_, err = c.Call(ctx, "GET", "/almaws/v1/conf/jobs/"+url.PathEscape(jobID)+"/instances/"+url.PathEscape(instanceID), JSON, nil, &instance)
+	return instance, err
+}
+
+// JobInstanceWait checks the status of the job instance every interval until it finishes, and returns the finished instance.
+// The progress of the instance is shown on a progress bar. A failed check is retried, waiting one more interval
+// after each failure in a row. It stops waiting when the context is done, the threshold is reached,
+// or JobPollFailures checks in a row fail. The job instance keeps running in Alma when it stops waiting.
+func (c Client) JobInstanceWait(ctx context.Context, jobID, instanceID string, interval time.Duration) (instance JobInstance, err error) {
+	bar := DefaultProgressBar(100)
+	defer func() {
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Finish()
+	}()
+	failures := 0
+	for {
+		wait := interval
+		polled, err := c.JobInstance(ctx, jobID, instanceID)
+		var thresholdReached *ThresholdReachedError
+		switch {
+		case err == nil:
+			failures = 0
+			instance = polled
+			bar.Describe(fmt.Sprintf("Job %v %v", instanceID, instance.Status.Value))
+			_ = bar.Set(instance.Progress)
+			if !instance.Running() {
+				return instance, nil
+			}
+		case ctx.Err() != nil, errors.As(err, &thresholdReached):
+			return instance, err
+		default:
+			failures++
+			if failures >= JobPollFailures {
+				return instance, fmt.Errorf("checking instance %v of job %v failed %v times in a row: %w", instanceID, jobID, failures, err)
+			}
+			wait = time.Duration(failures+1) * interval
+			// Log is safe to use concurrently.
+			log.Printf("WARNING: Checking instance %v of job %v failed, %v. Retrying in %v.\n", instanceID, jobID, err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return instance, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// JobEventEntity is a record an event of a job instance is about, like a bib record.
+type JobEventEntity struct {
+	Type  Value  `json:"type"`
+	Value string `json:"value"`
+}
+
+// JobEvent is something which happened during a job instance, like a record being imported or rejected.
+type JobEvent struct {
+	Type        Value            `json:"type"`
+	Severity    Value            `json:"severity"`
+	Description string           `json:"description,omitempty"`
+	Date        string           `json:"date,omitempty"`
+	Entities    []JobEventEntity `json:"entity,omitempty"`
+}
+
+// JobEvents stores a page of job instance events.
+type JobEvents struct {
+	Events           []JobEvent `json:"event"`
+	TotalRecordCount int        `json:"total_record_count"`
+}
+
+// JobInstanceEvents returns the events of the job instance.
+func (c Client) JobInstanceEvents(ctx context.Context, jobID, instanceID string) (events []JobEvent, errs []error) {
+	get := func(ctx context.Context, offset int) (page JobEvents, err error) {
+		q := url.Values{}
+		q.Set("limit", strconv.Itoa(LimitParam))
+		q.Set("offset", strconv.Itoa(offset))
+		_, err = c.Call(ctx, "GET", "/almaws/v1/conf/jobs/"+url.PathEscape(jobID)+"/instances/"+url.PathEscape(instanceID)+"/events?"+q.Encode(),
+			JSON, nil, &page)
+		return page, err
+	}
+	first, err := get(ctx, 0)
+	if err != nil {
+		return events, []error{err}
+	}
+	events = append(events, first.Events...)
+	om := &sync.Mutex{}
+	errs = c.listPages(ctx, first.TotalRecordCount, "Getting job events", func(ctx context.Context, offset int) error {
+		page, err := get(ctx, offset)
+		if err != nil {
+			return err
+		}
+		om.Lock()
+		defer om.Unlock()
+		events = append(events, page.Events...)
+		return nil
+	})
+	return events, errs
+}
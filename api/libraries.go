@@ -16,29 +16,29 @@ import (
 // Library stores data about a library in Alma, which represents a physical library in the institution, which gives library services.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_library.xsd/
 type Library struct {
-	Link            string `xml:"link,attr"`
-	Code            string `xml:"code"`
-	Path            string `xml:"path"`
-	Name            string `xml:"name"`
-	Description     string `xml:"description"`
-	ResourceSharing string `xml:"resource_sharing"`
+	Link            string `xml:"link,attr" json:"link"`
+	Code            string `xml:"code" json:"code"`
+	Path            string `xml:"path" json:"path"`
+	Name            string `xml:"name" json:"name"`
+	Description     string `xml:"description" json:"description"`
+	ResourceSharing bool   `xml:"resource_sharing" json:"resource_sharing"`
 	Campus          struct {
-		Text string `xml:",chardata"`
-		Desc string `xml:"desc,attr"`
-	} `xml:"campus"`
-	Proxy           string `xml:"proxy"`
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"campus" json:"campus"`
+	Proxy           string `xml:"proxy" json:"proxy"`
 	DefaultLocation struct {
-		Text string `xml:",chardata"`
-		Desc string `xml:"desc,attr"`
-	} `xml:"default_location"`
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"default_location" json:"default_location"`
 }
 
 // Libraries stores data about libraries configured for the Institution.
 // This is a little different than []Library for XML unmarshalling.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_libraries.xsd/
 type Libraries struct {
-	XMLName   xml.Name  `xml:"libraries"`
-	Libraries []Library `xml:"library"`
+	XMLName   xml.Name  `xml:"libraries" json:"-"`
+	Libraries []Library `xml:"library" json:"library"`
 }
 
 // Libraries returns the Libraries configured for the Institution.
@@ -0,0 +1,79 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Location stores data about a physical location in a library.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_location.xsd/
+type Location struct {
+	Link         string `xml:"link,attr" json:"link"`
+	Code         string `xml:"code" json:"code"`
+	Name         string `xml:"name" json:"name"`
+	ExternalName string `xml:"external_name" json:"external_name"`
+	Type         struct {
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"type" json:"type"`
+	RemoteStorage          bool   `xml:"remote_storage" json:"remote_storage"`
+	Map                    string `xml:"map" json:"map"`
+	SuppressFromPublishing bool   `xml:"suppress_from_publishing" json:"suppress_from_publishing"`
+	FulfillmentUnit        struct {
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"fulfillment_unit" json:"fulfillment_unit"`
+	AccessionPlacement struct {
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"accession_placement" json:"accession_placement"`
+	CallNumberType struct {
+		Text string `xml:",chardata" json:"value"`
+		Desc string `xml:"desc,attr" json:"desc"`
+	} `xml:"call_number_type" json:"call_number_type"`
+}
+
+// Locations stores data about the locations in a library.
+// This is a little different than []Location for XML unmarshalling.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_locations.xsd/
+type Locations struct {
+	XMLName          xml.Name   `xml:"locations" json:"-"`
+	TotalRecordCount int        `xml:"total_record_count,attr" json:"total_record_count"`
+	Locations        []Location `xml:"location" json:"location"`
+}
+
+// Location returns the location with the given code, and whether it was found.
+func (l Locations) Location(code string) (location Location, found bool) {
+	for _, location := range l.Locations {
+		if location.Code == code {
+			return location, true
+		}
+	}
+	return location, false
+}
+
+// Locations returns the locations in the library.
+func (c Client) Locations(ctx context.Context, library string) (locations Locations, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/conf/libraries/"+url.PathEscape(library)+"/locations", nil)
+	if err != nil {
+		return locations, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return locations, err
+	}
+	err = xml.Unmarshal(body, &locations)
+	if err != nil {
+		return locations, fmt.Errorf("unmarshalling locations XML failed: %w\n%v", err, string(body))
+	}
+	return locations, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// MappingTable stores data about values which are mapped to other values.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_mapping_table.xsd/
+type MappingTable struct {
+	XMLName     xml.Name `xml:"mapping_table"`
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	SubSystem   struct {
+		Text string `xml:",chardata"`
+		Desc string `xml:"desc,attr"`
+	} `xml:"sub_system"`
+	Scope struct {
+		InstitutionID struct {
+			Text string `xml:",chardata"`
+			Desc string `xml:"desc,attr"`
+		} `xml:"institution_id"`
+		LibraryID struct {
+			Text string `xml:",chardata"`
+			Desc string `xml:"desc,attr"`
+		} `xml:"library_id"`
+	} `xml:"scope"`
+	Rows []MappingTableRow `xml:"rows>row"`
+	// Other stores the elements of the table which aren't modelled above.
+	Other []XMLElement `xml:",any"`
+}
+
+// MappingTableRow stores data about a row in a mapping table.
+// The meaning of each column depends on the table.
+type MappingTableRow struct {
+	Column0 string `xml:"column0"`
+	Column1 string `xml:"column1"`
+	Column2 string `xml:"column2"`
+	Enabled string `xml:"enabled"`
+	// Other stores the other fields of the row, like column3 and up.
+	Other []XMLText `xml:",any"`
+}
+
+// MappingTable returns a mapping table.
+func (c Client) MappingTable(ctx context.Context, name string) (table MappingTable, err error) {
+	r, err := http.NewRequest("GET", "/almaws/v1/conf/mapping-tables/"+url.PathEscape(name), nil)
+	if err != nil {
+		return table, err
+	}
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return table, err
+	}
+	err = xml.Unmarshal(body, &table)
+	if err != nil {
+		return table, fmt.Errorf("unmarshalling mapping table XML failed: %w\n%v", err, string(body))
+	}
+	return table, nil
+}
+
+// MappingTableUpdate PUTs the mapping table back to the API.
+func (c Client) MappingTableUpdate(ctx context.Context, table MappingTable) (updated MappingTable, err error) {
+	tableBytes, err := xml.Marshal(table)
+	if err != nil {
+		return updated, fmt.Errorf("marshalling mapping table XML failed: %w", err)
+	}
+	r, err := http.NewRequest("PUT", "/almaws/v1/conf/mapping-tables/"+url.PathEscape(table.Name), bytes.NewReader(tableBytes))
+	if err != nil {
+		return updated, err
+	}
+	r.Header.Add("Content-Type", "application/xml")
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return updated, err
+	}
+	err = xml.Unmarshal(body, &updated)
+	if err != nil {
+		return updated, fmt.Errorf("unmarshalling mapping table XML failed: %w\n%v", err, string(body))
+	}
+	return updated, nil
+}
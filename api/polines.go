@@ -0,0 +1,219 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Amount is an amount of money in a currency.
+type Amount struct {
+	Sum      Decimal `json:"sum,omitempty"`
+	Currency Value   `json:"currency"`
+}
+
+// FundDistribution is the part of a PO line's price paid from a fund.
+type FundDistribution struct {
+	FundCode Value   `json:"fund_code"`
+	Percent  Decimal `json:"percent,omitempty"`
+	Amount   Amount  `json:"amount"`
+}
+
+// POLine stores data about a purchase order line, which is read and updated as JSON.
+// Fields which are not in the struct are kept in Raw, and are sent back unchanged when the line is updated.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_po_line.xsd/
+type POLine struct {
+	Number                string             `json:"number,omitempty"`
+	PONumber              string             `json:"po_number,omitempty"`
+	Type                  Value              `json:"type"`
+	Status                Value              `json:"status"`
+	Owner                 Value              `json:"owner"`
+	Vendor                Value              `json:"vendor"`
+	VendorAccount         string             `json:"vendor_account,omitempty"`
+	VendorReferenceNumber string             `json:"vendor_reference_number,omitempty"`
+	AcquisitionMethod     Value              `json:"acquisition_method"`
+	Price                 Amount             `json:"price"`
+	FundDistribution      []FundDistribution `json:"fund_distribution,omitempty"`
+	ResourceMetadata      struct {
+		Title  string `json:"title,omitempty"`
+		Author string `json:"author,omitempty"`
+		ISBN   string `json:"isbn,omitempty"`
+		ISSN   string `json:"issn,omitempty"`
+		MMSID  Value  `json:"mms_id"`
+	} `json:"resource_metadata"`
+	ExpectedReceiptDate     string  `json:"expected_receipt_date,omitempty"`
+	ExpectedReceiptInterval Decimal `json:"expected_receipt_interval,omitempty"`
+	ClaimingInterval        Decimal `json:"claiming_interval,omitempty"`
+	CreatedDate             string  `json:"created_date,omitempty"`
+	Note                    []struct {
+		NoteText string `json:"note_text,omitempty"`
+	} `json:"note,omitempty"`
+	// Raw is the JSON of the PO line returned by the API.
+	Raw []byte `json:"-"`
+}
+
+// Overdue returns true if the line has an expected receipt date before the date, which is formatted like 2006-01-02.
+// Claims are usually sent for lines which are overdue.
+func (p POLine) Overdue(date string) bool {
+	if len(p.ExpectedReceiptDate) < len("2006-01-02") {
+		return false
+	}
+	return p.ExpectedReceiptDate[:len("2006-01-02")] < date
+}
+
+// POLines stores a page of PO lines.
+type POLines struct {
+	POLines          []POLine `json:"po_line"`
+	TotalRecordCount int      `json:"total_record_count"`
+}
+
+// POLines returns the PO lines with the status, like ACTIVE or ALL, which match the query.
+// The query uses Alma's q parameter syntax, like 'vendor_account~ACCOUNT', and can be empty.
+func (c Client) POLines(ctx context.Context, status, query string) (lines []POLine, errs []error) {
+	get := func(ctx context.Context, offset int) (page POLines, err error) {
+		q := url.Values{}
+		if status != "" {
+			q.Set("status", status)
+		}
+		if query != "" {
+			q.Set("q", query)
+		}
+		q.Set("limit", strconv.Itoa(LimitParam))
+		q.Set("offset", strconv.Itoa(offset))
+		_, err = c.Call(ctx, "GET", "/almaws/v1/acq/po-lines?"+q.Encode(), JSON, nil, &page)
+		return page, err
+	}
+	first, err := get(ctx, 0)
+	if err != nil {
+		return lines, []error{err}
+	}
+	lines = append(lines, first.POLines...)
+	om := &sync.Mutex{}
+	errs = c.listPages(ctx, first.TotalRecordCount, "Getting PO lines", func(ctx context.Context, offset int) error {
+		page, err := get(ctx, offset)
+		if err != nil {
+			return err
+		}
+		om.Lock()
+		defer om.Unlock()
+		lines = append(lines, page.POLines...)
+		return nil
+	})
+	return lines, errs
+}
+
+// POLine returns the PO line with the number.
+func (c Client) POLine(ctx context.Context, number string) (line POLine, err error) {
+	body, err := c.Call(ctx, "GET", "/almaws/v1/acq/po-lines/"+url.PathEscape(number), JSON, nil, &line)
+	if err != nil {
+		return line, err
+	}
+	line.Raw = body
+	return line, nil
+}
+
+// POLineUpdate PUTs the PO line back to the API.
+// If the line has Raw JSON, the fields of the struct are merged into it, so fields not in the struct are kept.
+// Lines returned by POLines have no Raw JSON, so get the line with POLine before changing it.
+func (c Client) POLineUpdate(ctx context.Context, line POLine) (updated POLine, err error) {
+	var in interface{} = line
+	if len(line.Raw) != 0 {
+		in, err = mergedJSON(line.Raw, line)
+		if err != nil {
+			return updated, err
+		}
+	}
+	body, err := c.Call(ctx, "PUT", "/almaws/v1/acq/po-lines/"+url.PathEscape(line.Number), JSON, in, &updated)
+	if err != nil {
+		return updated, err
+	}
+	updated.Raw = body
+	return updated, nil
+}
+
+// ReceiveOptions stores the parameters of a receive operation.
+type ReceiveOptions struct {
+	// Department is the code of the receiving department.
+	Department string
+	// Library is the code of the library the receiving department is in.
+	Library string
+	// ReceiveDate is the date the item was received, like 2006-01-02. Alma uses today if it is empty.
+	ReceiveDate string
+}
+
+// query adds the receive operation parameters to the query.
+func (o ReceiveOptions) query(q url.Values) {
+	q.Set("op", "receive")
+	q.Set("department", o.Department)
+	q.Set("department_library", o.Library)
+	if o.ReceiveDate != "" {
+		q.Set("receive_date", o.ReceiveDate+"Z")
+	}
+}
+
+// POLineItemsReceive receives the items on the PO lines in their POLine fields.
+func (c Client) POLineItemsReceive(ctx context.Context, items []Item, options ReceiveOptions) (received []Item, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(items), "Receiving items")
+	defer cancel()
+	for _, item := range items {
+		item := item // avoid closure refering to wrong value
+		jobs <- func() {
+			receivedItem, err := c.POLineItemReceive(ctx, item, options)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				received = append(received, receivedItem)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return received, errs
+}
+
+// POLineItemReceive POSTs the receive operation for an existing item on the PO line in its POLine field.
+func (c Client) POLineItemReceive(ctx context.Context, item Item, options ReceiveOptions) (received Item, err error) {
+	if item.POLine == "" {
+		return received, fmt.Errorf("the item with barcode '%v' is not on a PO line", item.Barcode)
+	}
+	q := url.Values{}
+	options.query(q)
+	path := "/almaws/v1/acq/po-lines/" + url.PathEscape(item.POLine) + "/items/" + url.PathEscape(item.PID) + "?" + q.Encode()
+	r, err := http.NewRequest("POST", path, strings.NewReader("<item/>"))
+	if err != nil {
+		return received, err
+	}
+	r.Header.Add("Content-Type", "application/xml")
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return received, fmt.Errorf("receiving the item with barcode '%v' on PO line %v failed: %w", item.Barcode, item.POLine, err)
+	}
+	err = xml.Unmarshal(body, &received)
+	if err != nil {
+		return received, fmt.Errorf("unmarshalling item XML failed: %w\n%v", err, string(body))
+	}
+	received.Link = received.Path()
+	return received, nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The structs in this package only model some of the elements Alma returns.
+// To avoid dropping the other elements when a record is updated, the raw XML
+// of the record is kept, and only the text of the elements which changed is
+// replaced before the record is sent back.
+
+// XMLText is an element which holds only text. Elements which aren't modelled
+// by a struct are kept in a slice of XMLText, so they are sent back unchanged.
+type XMLText struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+}
+
+// XMLElement is an element which isn't modelled by a struct, kept with its
+// attributes and contents so it is sent back unchanged.
+type XMLElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// xmlLeaf is an element in an XML document which holds only text.
+type xmlLeaf struct {
+	path     string // The names of the element and its ancestors, with the index of each among its siblings of the same name.
+	tagStart int64  // The offset of the start tag.
+	start    int64  // The offset of the text, after the start tag.
+	end      int64  // The offset of the end tag.
+	text     string // The unescaped text.
+}
+
+// xmlLeaves returns the elements in the document which hold only text, in document order.
+func xmlLeaves(doc []byte) (leaves []xmlLeaf, err error) {
+	type element struct {
+		path     string
+		counts   map[string]int
+		tagStart int64
+		start    int64
+		leaf     bool
+		text     strings.Builder
+	}
+	stack := []*element{{counts: map[string]int{}}}
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		before := d.InputOffset()
+		token, err := d.Token()
+		if err == io.EOF {
+			return leaves, nil
+		}
+		if err != nil {
+			return leaves, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			parent.leaf = false
+			index := parent.counts[t.Name.Local]
+			parent.counts[t.Name.Local]++
+			stack = append(stack, &element{
+				path:     parent.path + "/" + t.Name.Local + "[" + strconv.Itoa(index) + "]",
+				counts:   map[string]int{},
+				tagStart: before,
+				start:    d.InputOffset(),
+				leaf:     true,
+			})
+		case xml.CharData:
+			parent.text.Write(t)
+		case xml.EndElement:
+			if parent.leaf {
+				leaves = append(leaves, xmlLeaf{parent.path, parent.tagStart, parent.start, before, parent.text.String()})
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// patchXML returns the original document, with the text of elements replaced where
+// it differs from the text in the modified document. Everything else in the original
+// document, including elements and attributes missing from the modified document,
+// is kept byte for byte. Elements are matched by their path in each document.
+func patchXML(original, modified []byte) (patched []byte, err error) {
+	originalLeaves, err := xmlLeaves(original)
+	if err != nil {
+		return patched, fmt.Errorf("reading the original XML failed: %w", err)
+	}
+	modifiedLeaves, err := xmlLeaves(modified)
+	if err != nil {
+		return patched, fmt.Errorf("reading the modified XML failed: %w", err)
+	}
+	originalMap := map[string]xmlLeaf{}
+	for _, leaf := range originalLeaves {
+		originalMap[leaf.path] = leaf
+	}
+	type replacement struct {
+		leaf xmlLeaf
+		text []byte
+	}
+	replacements := []replacement{}
+	for _, leaf := range modifiedLeaves {
+		originalLeaf, found := originalMap[leaf.path]
+		if !found {
+			if leaf.text == "" {
+				// Elements in the struct which were not in the record are marshalled empty.
+				continue
+			}
+			return patched, fmt.Errorf("the element %v is not in the original record, only the text of existing elements can be changed", leaf.path)
+		}
+		if originalLeaf.text != leaf.text {
+			replacements = append(replacements, replacement{originalLeaf, modified[leaf.start:leaf.end]})
+		}
+	}
+	if len(replacements) == 0 {
+		return original, nil
+	}
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].leaf.start < replacements[j].leaf.start
+	})
+	var b bytes.Buffer
+	var last int64
+	for _, r := range replacements {
+		if r.leaf.start == r.leaf.end && bytes.HasSuffix(original[:r.leaf.start], []byte("/>")) {
+			// A self-closing element, like <note/>, needs an end tag to hold text.
+			tag := bytes.TrimRight(original[r.leaf.tagStart:r.leaf.start-2], " \t\r\n")
+			name := strings.Fields(string(tag[1:]))[0]
+			b.Write(original[last:r.leaf.tagStart])
+			b.Write(tag)
+			b.WriteString(">")
+			b.Write(r.text)
+			b.WriteString("</" + name + ">")
+		} else {
+			b.Write(original[last:r.leaf.start])
+			b.Write(r.text)
+		}
+		last = r.leaf.end
+	}
+	b.Write(original[last:])
+	return b.Bytes(), nil
+}
+
+// mergeJSON returns the original JSON object with the fields of the modified JSON object merged into it.
+// Fields which are objects in both are merged recursively, and fields which are arrays in both are merged
+// element by element, matched by position; other fields are replaced.
+// Fields in the original object which are missing from the modified object are kept.
+func mergeJSON(original, modified []byte) (merged []byte, err error) {
+	var o, m map[string]interface{}
+	// Numbers are kept as json.Number so they aren't rounded.
+	d := json.NewDecoder(bytes.NewReader(original))
+	d.UseNumber()
+	err = d.Decode(&o)
+	if err != nil {
+		return merged, fmt.Errorf("reading the original JSON failed: %w", err)
+	}
+	d = json.NewDecoder(bytes.NewReader(modified))
+	d.UseNumber()
+	err = d.Decode(&m)
+	if err != nil {
+		return merged, fmt.Errorf("reading the modified JSON failed: %w", err)
+	}
+	mergeJSONObjects(o, m)
+	return json.Marshal(o)
+}
+
+// mergeJSONObjects merges the fields of modified into original.
+func mergeJSONObjects(original, modified map[string]interface{}) {
+	for key, value := range modified {
+		original[key] = mergeJSONValues(original[key], value)
+	}
+}
+
+// mergeJSONValues returns the modified value merged into the original value.
+// Elements of arrays are matched by position, so the fields which aren't modelled in an element, like the
+// amount of a fund distribution, stay with the element in the same position. The merged array has the
+// length of the modified array, elements removed from the end are dropped and elements added to the end are kept.
+func mergeJSONValues(original, modified interface{}) interface{} {
+	switch m := modified.(type) {
+	case map[string]interface{}:
+		if o, ok := original.(map[string]interface{}); ok {
+			mergeJSONObjects(o, m)
+			return o
+		}
+	case []interface{}:
+		if o, ok := original.([]interface{}); ok {
+			merged := make([]interface{}, len(m))
+			for i := range m {
+				if i < len(o) {
+					merged[i] = mergeJSONValues(o[i], m[i])
+				} else {
+					merged[i] = m[i]
+				}
+			}
+			return merged
+		}
+	}
+	return modified
+}
+
+// mergedJSON returns the raw JSON with v merged into it, ready to be marshalled as the body of a request.
+func mergedJSON(raw []byte, v interface{}) (merged json.RawMessage, err error) {
+	modified, err := json.Marshal(v)
+	if err != nil {
+		return merged, fmt.Errorf("marshalling JSON failed: %w", err)
+	}
+	merged, err = mergeJSON(raw, modified)
+	if err != nil {
+		return merged, fmt.Errorf("merging JSON failed: %w", err)
+	}
+	return merged, nil
+}
+
+// replacedJSON returns the JSON object with the field at the path, like coverage_details then
+// local_date_coverage_parameters, replaced by v instead of merged with it.
+// It's used for fields like date ranges, where keeping the original values of the parts which were left out would change their meaning.
+func replacedJSON(object json.RawMessage, v interface{}, path ...string) (replaced json.RawMessage, err error) {
+	var o map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(object))
+	d.UseNumber()
+	err = d.Decode(&o)
+	if err != nil {
+		return replaced, fmt.Errorf("reading the JSON failed: %w", err)
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return replaced, fmt.Errorf("marshalling JSON failed: %w", err)
+	}
+	var field interface{}
+	d = json.NewDecoder(bytes.NewReader(value))
+	d.UseNumber()
+	err = d.Decode(&field)
+	if err != nil {
+		return replaced, fmt.Errorf("reading the replacement JSON failed: %w", err)
+	}
+	parent := o
+	for _, key := range path[:len(path)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			parent[key] = child
+		}
+		parent = child
+	}
+	parent[path[len(path)-1]] = field
+	return json.Marshal(o)
+}
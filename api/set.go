@@ -39,16 +39,16 @@ type Sets struct {
 // Member stores data about members of sets.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_member.xsd/
 type Member struct {
-	ID   string `xml:"id"`
-	Link string `xml:"link,attr"`
+	ID   string `xml:"id" json:"id"`
+	Link string `xml:"link,attr" json:"link"`
 }
 
 // Members stores data about set members.
 // This is a little different than []Member for safer XML unmarshalling.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_members.xsd/
 type Members struct {
-	XMLName xml.Name `xml:"members"` //the XML root element must have the name "members" or else Unmarshal returns an error.
-	Members []Member `xml:"member"`
+	XMLName xml.Name `xml:"members" json:"-"` //the XML root element must have the name "members" or else Unmarshal returns an error.
+	Members []Member `xml:"member" json:"member"`
 }
 
 // SetFromNameOrID returns the set when provided the name or ID.
@@ -113,16 +113,22 @@ func (c Client) SetFromID(ctx context.Context, ID string) (set Set, err error) {
 	return set, nil
 }
 
+// Pages returns the number of API calls needed to get the members of the set, LimitParam members at a time.
+func (s Set) Pages() int {
+	pages := s.NumberOfMembers / LimitParam
+	// If there's a remainder, we need the extra offset.
+	if s.NumberOfMembers%LimitParam != 0 {
+		pages++
+	}
+	return pages
+}
+
 // SetMembers returns the members of a set.
 func (c Client) SetMembers(ctx context.Context, set Set) (members []Member, errs []error) {
 	// With a map, we can ensure we don't get duplicate members from the API.
 	membersMap := map[string]Member{}
-	offsets := set.NumberOfMembers / LimitParam
-	// If there's a remainder, we need the extra offset.
-	if set.NumberOfMembers%LimitParam != 0 {
-		offsets++
-	}
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, offsets, "Getting set members")
+	offsets := set.Pages()
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, offsets, "Getting set members")
 	defer cancel()
 	for i := 0; i < offsets; i++ {
 		offset := i * LimitParam
@@ -0,0 +1,151 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// groupedAreas are the areas of the API where the segment after the area names a kind of resource,
+// like /almaws/v1/conf/libraries, rather than identifying a resource, like /almaws/v1/bibs/{id}.
+var groupedAreas = map[string]bool{
+	"acq":        true,
+	"analytics":  true,
+	"conf":       true,
+	"electronic": true,
+	"rs":         true,
+	"task-lists": true,
+}
+
+// Endpoint returns the path with the segments which identify resources replaced by {id},
+// so calls for different records are counted together.
+// For example, /almaws/v1/bibs/99123/holdings/22456 becomes /almaws/v1/bibs/{id}/holdings/{id}.
+func Endpoint(path string) string {
+	const prefix = "/almaws/v1/"
+	if !strings.HasPrefix(path, prefix) {
+		return path
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, prefix), "/"), "/")
+	// The index of the first segment which is a kind of resource, after the area.
+	first := 0
+	if groupedAreas[segments[0]] {
+		first = 1
+	}
+	for i := first + 1; i < len(segments); i += 2 {
+		if segments[i] != "test" {
+			segments[i] = "{id}"
+		}
+	}
+	return prefix + strings.Join(segments, "/")
+}
+
+// EndpointCount is the number of calls made to an endpoint with a method.
+type EndpointCount struct {
+	Method   string
+	Endpoint string
+	Calls    int
+}
+
+// RemainingSample is the number of API calls remaining for the day, from the X-Exl-Api-Remaining header.
+type RemainingSample struct {
+	Time      time.Time
+	Remaining int
+}
+
+// Usage counts the API calls made by a Client. It is safe for concurrent use.
+type Usage struct {
+	mu        sync.Mutex
+	started   time.Time
+	counts    map[EndpointCount]int
+	remaining []RemainingSample
+}
+
+// NewUsage returns a new Usage, started now.
+func NewUsage() *Usage {
+	return &Usage{
+		started: time.Now(),
+		counts:  map[EndpointCount]int{},
+	}
+}
+
+// Record counts a call, and the remaining calls if they were returned by the API.
+func (u *Usage) Record(method, path string, remaining int, hasRemaining bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.counts[EndpointCount{Method: method, Endpoint: Endpoint(path)}]++
+	if hasRemaining {
+		u.remaining = append(u.remaining, RemainingSample{time.Now(), remaining})
+	}
+}
+
+// Calls returns the number of calls made to each endpoint, sorted by the number of calls, most first.
+func (u *Usage) Calls() (counts []EndpointCount) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for key, calls := range u.counts {
+		key.Calls = calls
+		counts = append(counts, key)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Calls != counts[j].Calls {
+			return counts[i].Calls > counts[j].Calls
+		}
+		if counts[i].Endpoint != counts[j].Endpoint {
+			return counts[i].Endpoint < counts[j].Endpoint
+		}
+		return counts[i].Method < counts[j].Method
+	})
+	return counts
+}
+
+// Total returns the number of calls made.
+func (u *Usage) Total() (total int) {
+	for _, count := range u.Calls() {
+		total += count.Calls
+	}
+	return total
+}
+
+// Remaining returns the samples of the remaining calls, in the order they were recorded.
+func (u *Usage) Remaining() []RemainingSample {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]RemainingSample{}, u.remaining...)
+}
+
+// LastRemaining returns the most recent number of remaining calls, and whether any were recorded.
+func (u *Usage) LastRemaining() (remaining int, found bool) {
+	samples := u.Remaining()
+	if len(samples) == 0 {
+		return remaining, false
+	}
+	return samples[len(samples)-1].Remaining, true
+}
+
+// Summary writes the number of calls per endpoint, and how the remaining calls changed.
+func (u *Usage) Summary(w io.Writer) {
+	calls := u.Calls()
+	total := 0
+	for _, count := range calls {
+		total += count.Calls
+	}
+	elapsed := time.Since(u.started).Round(time.Second)
+	fmt.Fprintf(w, "API usage: %v call(s) in %v.\n", total, elapsed)
+	for _, count := range calls {
+		fmt.Fprintf(w, "  %7d %-6v %v\n", count.Calls, count.Method, count.Endpoint)
+	}
+	samples := u.Remaining()
+	if len(samples) != 0 {
+		first, last := samples[0], samples[len(samples)-1]
+		fmt.Fprintf(w, "Remaining API calls for the day went from %v to %v.\n", first.Remaining, last.Remaining)
+	}
+}
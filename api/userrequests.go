@@ -7,6 +7,7 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"errors"
@@ -18,9 +19,13 @@ import (
 // UserRequest stores data about a user request on items.
 // https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_user_request.xsd/
 type UserRequest struct {
-	ID      string `xml:"request_id"`
-	Type    string `xml:"request_type"`
-	SubType string `xml:"request_sub_type"`
+	XMLName           xml.Name `xml:"user_request"`
+	ID                string   `xml:"request_id,omitempty"`
+	Type              string   `xml:"request_type"`
+	SubType           string   `xml:"request_sub_type,omitempty"`
+	Status            string   `xml:"request_status,omitempty"`
+	TargetDestination string   `xml:"target_destination,omitempty"`
+	Comment           string   `xml:"comment,omitempty"`
 	// Member is an optional field for the 'originating' item member.
 	Member Member `xml:"-"`
 	// Link is an optional field for the user request link.
@@ -46,7 +51,7 @@ type UserRequests struct {
 
 // ItemMembersUserRequests returns user requests on item members.
 func (c Client) ItemMembersUserRequests(ctx context.Context, members []Member) (requests []UserRequest, errs []error) {
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(members), "Getting user requests")
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(members), "Getting user requests")
 	defer cancel()
 	for _, member := range members {
 		member := member // avoid closure refering to wrong value
@@ -103,7 +108,7 @@ func (c Client) ItemMemberUserRequests(ctx context.Context, member Member) (requ
 
 // UserRequestsCancel cancels user requests.
 func (c Client) UserRequestsCancel(ctx context.Context, requests []UserRequest, reason, note string) (cancelled []UserRequest, errs []error) {
-	ctx, cancel, em, om, jobs, wg, bar := StartConcurrent(ctx, len(requests), "Cancelling user requests")
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(requests), "Cancelling user requests")
 	defer cancel()
 	for _, request := range requests {
 		request := request // avoid closure refering to wrong value
@@ -154,3 +159,62 @@ func (c Client) UserRequestCancel(ctx context.Context, request UserRequest, reas
 	}
 	return nil
 }
+
+// ItemMembersCreateRequest creates a copy of the user request on each item member.
+func (c Client) ItemMembersCreateRequest(ctx context.Context, members []Member, request UserRequest) (created []UserRequest, errs []error) {
+	ctx, cancel, em, om, jobs, wg, bar := c.StartConcurrent(ctx, len(members), "Creating user requests")
+	defer cancel()
+	for _, member := range members {
+		member := member // avoid closure refering to wrong value
+		jobs <- func() {
+			itemRequest, err := c.ItemMemberCreateRequest(ctx, member, request)
+			if err != nil {
+				var over *ThresholdReachedError
+				if errors.As(err, &over) {
+					// We've reached the threshold, cancel the context.
+					cancel()
+				}
+				em.Lock()
+				defer em.Unlock()
+				errs = append(errs, err)
+			} else {
+				om.Lock()
+				defer om.Unlock()
+				created = append(created, itemRequest)
+			}
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	StopConcurrent(jobs, wg)
+	return created, errs
+}
+
+// ItemMemberCreateRequest POSTs the user request to an item member.
+// Requests which do not belong to a patron, like WORK_ORDER and MOVE, can be created this way.
+func (c Client) ItemMemberCreateRequest(ctx context.Context, member Member, request UserRequest) (created UserRequest, err error) {
+	url, err := url.Parse(member.Link + "/requests")
+	if err != nil {
+		return created, err
+	}
+	requestBytes, err := xml.Marshal(request)
+	if err != nil {
+		return created, fmt.Errorf("marshalling user request XML failed: %w", err)
+	}
+	r, err := http.NewRequest("POST", url.String(), bytes.NewReader(requestBytes))
+	if err != nil {
+		return created, err
+	}
+	r.Header.Add("Content-Type", "application/xml")
+	body, err := c.Do(ctx, r)
+	if err != nil {
+		return created, err
+	}
+	err = xml.Unmarshal(body, &created)
+	if err != nil {
+		return created, fmt.Errorf("unmarshalling user request XML failed: %w\n%v", err, string(body))
+	}
+	created.Member = member
+	created.Link = member.Link + "/requests/" + created.ID
+	return created, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package api provides an HTTP client which works with the Alma API.
+package api
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VendorAccount is an account the institution has with a vendor.
+type VendorAccount struct {
+	ID            string  `json:"account_id,omitempty"`
+	Code          string  `json:"code,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Status        Value   `json:"status"`
+	PaymentMethod []Value `json:"payment_method,omitempty"`
+}
+
+// ContactInfo stores the addresses, emails, phone numbers, and web addresses of a vendor.
+type ContactInfo struct {
+	Address []struct {
+		Preferred     bool   `json:"preferred"`
+		Line1         string `json:"line1,omitempty"`
+		Line2         string `json:"line2,omitempty"`
+		Line3         string `json:"line3,omitempty"`
+		City          string `json:"city,omitempty"`
+		StateProvince string `json:"state_province,omitempty"`
+		PostalCode    string `json:"postal_code,omitempty"`
+		Country       Value  `json:"country"`
+	} `json:"address,omitempty"`
+	Email []struct {
+		Preferred    bool   `json:"preferred"`
+		EmailAddress string `json:"email_address,omitempty"`
+	} `json:"email,omitempty"`
+	Phone []struct {
+		Preferred   bool   `json:"preferred"`
+		PhoneNumber string `json:"phone_number,omitempty"`
+	} `json:"phone,omitempty"`
+	WebAddress []struct {
+		URL string `json:"url,omitempty"`
+	} `json:"web_address,omitempty"`
+}
+
+// PreferredEmail returns the preferred email address, or the first if none are preferred.
+func (c ContactInfo) PreferredEmail() string {
+	for _, email := range c.Email {
+		if email.Preferred {
+			return email.EmailAddress
+		}
+	}
+	if len(c.Email) != 0 {
+		return c.Email[0].EmailAddress
+	}
+	return ""
+}
+
+// PreferredPhone returns the preferred phone number, or the first if none are preferred.
+func (c ContactInfo) PreferredPhone() string {
+	for _, phone := range c.Phone {
+		if phone.Preferred {
+			return phone.PhoneNumber
+		}
+	}
+	if len(c.Phone) != 0 {
+		return c.Phone[0].PhoneNumber
+	}
+	return ""
+}
+
+// PreferredAddress returns the preferred address on one line, or the first if none are preferred.
+func (c ContactInfo) PreferredAddress() string {
+	if len(c.Address) == 0 {
+		return ""
+	}
+	address := c.Address[0]
+	for _, a := range c.Address {
+		if a.Preferred {
+			address = a
+			break
+		}
+	}
+	parts := []string{}
+	for _, part := range []string{address.Line1, address.Line2, address.Line3, address.City,
+		address.StateProvince, address.PostalCode, address.Country.Value} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Vendor stores data about a vendor, which is read as JSON.
+// https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_vendor.xsd/
+type Vendor struct {
+	Code             string          `json:"code,omitempty"`
+	Name             string          `json:"name,omitempty"`
+	Status           Value           `json:"status"`
+	Language         Value           `json:"language"`
+	MaterialSupplier bool            `json:"material_supplier"`
+	AccessProvider   bool            `json:"access_provider"`
+	Licensor         bool            `json:"licensor"`
+	Governmental     bool            `json:"governmental"`
+	Currency         []Value         `json:"currency,omitempty"`
+	Accounts         []VendorAccount `json:"account,omitempty"`
+	ContactInfo      ContactInfo     `json:"contact_info"`
+}
+
+// Vendors stores a page of vendors.
+type Vendors struct {
+	Vendors          []Vendor `json:"vendor"`
+	TotalRecordCount int      `json:"total_record_count"`
+}
+
+// Vendors returns the vendors with the status, ACTIVE, INACTIVE, or ALL.
+func (c Client) Vendors(ctx context.Context, status string) (vendors []Vendor, errs []error) {
+	get := func(ctx context.Context, offset int) (page Vendors, err error) {
+		q := url.Values{}
+		if status != "" {
+			q.Set("status", status)
+		}
+		q.Set("limit", strconv.Itoa(LimitParam))
+		q.Set("offset", strconv.Itoa(offset))
+		_, err = c.Call(ctx, "GET", "/almaws/v1/acq/vendors?"+q.Encode(), JSON, nil, &page)
+		return page, err
+	}
+	first, err := get(ctx, 0)
+	if err != nil {
+		return vendors, []error{err}
+	}
+	vendors = append(vendors, first.Vendors...)
+	om := &sync.Mutex{}
+	errs = c.listPages(ctx, first.TotalRecordCount, "Getting vendors", func(ctx context.Context, offset int) error {
+		page, err := get(ctx, offset)
+		if err != nil {
+			return err
+		}
+		om.Lock()
+		defer om.Unlock()
+		vendors = append(vendors, page.Vendors...)
+		return nil
+	})
+	return vendors, errs
+}
+
+// Vendor returns the vendor with the code.
+func (c Client) Vendor(ctx context.Context, code string) (vendor Vendor, err error) {
+	_, err = c.Call(ctx, "GET", "/almaws/v1/acq/vendors/"+url.PathEscape(code), JSON, nil, &vendor)
+	return vendor, err
+}
@@ -6,4 +6,5 @@ require (
 	github.com/cu-library/overridefromenv v1.2.0
 	github.com/schollz/progressbar/v3 v3.6.2
 	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
+	gopkg.in/yaml.v2 v2.4.0
 )
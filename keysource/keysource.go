@@ -0,0 +1,116 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package keysource reads the Alma API key from places other than the command line,
+// so the key doesn't end up in shell history or process listings.
+package keysource
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// SecretServiceAttribute is the attribute used to look up keys stored with secret-tool.
+// Keys can be stored with: secret-tool store --label="Alma API key" almatoolkit NAME
+const SecretServiceAttribute = "almatoolkit"
+
+// Sources stores the places the API key can be read from. Only one should be set.
+type Sources struct {
+	Flag          string // The key itself, from the key flag or environment variable.
+	File          string // The path to a file holding the key.
+	Command       string // A command which prints the key, like a password manager CLI.
+	SecretService string // The name of a key stored in the Secret Service with secret-tool.
+	Pass          string // The name of a key stored with the pass password manager.
+}
+
+// Key returns the API key from the source which is set.
+func (s Sources) Key() (key string, err error) {
+	set := 0
+	for _, source := range []string{s.Flag, s.File, s.Command, s.SecretService, s.Pass} {
+		if source != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return key, fmt.Errorf("an Alma API key is required")
+	case set > 1:
+		return key, fmt.Errorf("the API key can only be read from one source, but %v were set", set)
+	case s.File != "":
+		key, err = FromFile(s.File)
+	case s.Command != "":
+		key, err = FromCommand(s.Command)
+	case s.SecretService != "":
+		key, err = FromSecretService(s.SecretService)
+	case s.Pass != "":
+		key, err = FromPass(s.Pass)
+	default:
+		key = s.Flag
+	}
+	if err != nil {
+		return key, err
+	}
+	if key == "" {
+		return key, fmt.Errorf("the API key read was empty")
+	}
+	return key, nil
+}
+
+// FromFile returns the key stored in the file, without surrounding whitespace.
+// A warning is logged if other users can read the file.
+func FromFile(path string) (key string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return key, fmt.Errorf("reading the API key file failed: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		log.Printf("WARNING: The API key file %v can be read by other users, consider running 'chmod 600 %v'.\n", path, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return key, fmt.Errorf("reading the API key file failed: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// FromCommand runs the command with the system shell and returns the first line it prints.
+func FromCommand(command string) (key string, err error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	return firstLine(cmd)
+}
+
+// FromSecretService returns the key stored under the name in the Secret Service (GNOME Keyring, KWallet),
+// using the secret-tool command.
+func FromSecretService(name string) (key string, err error) {
+	return firstLine(exec.Command("secret-tool", "lookup", SecretServiceAttribute, name))
+}
+
+// FromPass returns the key stored under the name in the pass password manager.
+func FromPass(name string) (key string, err error) {
+	return firstLine(exec.Command("pass", "show", name))
+}
+
+// firstLine runs the command and returns the first line of its output.
+// The output isn't included in errors, in case it holds the key.
+func firstLine(cmd *exec.Cmd) (line string, err error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return line, fmt.Errorf("running '%v' to read the API key failed: %w\n%v", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), nil
+}
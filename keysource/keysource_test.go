@@ -0,0 +1,71 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package keysource reads the Alma API key from places other than the command line,
+// so the key doesn't end up in shell history or process listings.
+package keysource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestKeyFromFile checks that the key is read from a file without surrounding whitespace.
+func TestKeyFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keysource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key")
+	err = ioutil.WriteFile(path, []byte("  abc123\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Sources{File: path}.Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "abc123" {
+		t.Fatalf("unexpected key %q", key)
+	}
+}
+
+// TestKeyFromCommand checks that the first line printed by a command is used as the key.
+func TestKeyFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command requires a POSIX shell")
+	}
+	key, err := Sources{Command: "printf 'abc123\\nsecond line\\n'"}.Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "abc123" {
+		t.Fatalf("unexpected key %q", key)
+	}
+	_, err = Sources{Command: "exit 1"}.Key()
+	if err == nil {
+		t.Fatal("expected an error from a failing command")
+	}
+}
+
+// TestKeySources checks that exactly one source must be set.
+func TestKeySources(t *testing.T) {
+	key, err := Sources{Flag: "abc123"}.Key()
+	if err != nil || key != "abc123" {
+		t.Fatalf("unexpected result %q, %v", key, err)
+	}
+	_, err = Sources{}.Key()
+	if err == nil {
+		t.Fatal("expected an error when no source is set")
+	}
+	_, err = Sources{Flag: "abc123", File: "key"}.Key()
+	if err == nil {
+		t.Fatal("expected an error when more than one source is set")
+	}
+}
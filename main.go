@@ -20,12 +20,30 @@ import (
 	"github.com/cu-library/overridefromenv"
 
 	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/keysource"
+	"github.com/cu-library/almatoolkit/profile"
+	"github.com/cu-library/almatoolkit/shared"
 	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/acq/fundsreport"
+	"github.com/cu-library/almatoolkit/subcommand/acq/invoiceimport"
+	"github.com/cu-library/almatoolkit/subcommand/acq/polreport"
+	"github.com/cu-library/almatoolkit/subcommand/acq/receive"
+	"github.com/cu-library/almatoolkit/subcommand/acq/vendorsreport"
+	"github.com/cu-library/almatoolkit/subcommand/analytics/export"
 	"github.com/cu-library/almatoolkit/subcommand/bibs/cleanupcallnumbers"
 	"github.com/cu-library/almatoolkit/subcommand/bibs/items/cancelrequests"
+	"github.com/cu-library/almatoolkit/subcommand/bibs/items/createrequest"
+	"github.com/cu-library/almatoolkit/subcommand/bibs/items/locationreport"
 	"github.com/cu-library/almatoolkit/subcommand/bibs/items/requests"
 	"github.com/cu-library/almatoolkit/subcommand/bibs/items/scanin"
+	"github.com/cu-library/almatoolkit/subcommand/conf/diff"
 	"github.com/cu-library/almatoolkit/subcommand/conf/dump"
+	"github.com/cu-library/almatoolkit/subcommand/conf/tableapply"
+	"github.com/cu-library/almatoolkit/subcommand/electronic/linkcheck"
+	"github.com/cu-library/almatoolkit/subcommand/electronic/portfoliosupdate"
+	"github.com/cu-library/almatoolkit/subcommand/jobs/importjob"
+	"github.com/cu-library/almatoolkit/subcommand/jobs/run"
+	"github.com/cu-library/almatoolkit/subcommand/keycheck"
 )
 
 const (
@@ -44,19 +62,51 @@ func main() {
 	log.SetFlags(0)
 
 	// Define the command line flags
-	key := flag.String("key", "", "The Alma API key. You can manage your API keys here: https://developers.exlibrisgroup.com/manage/keys/. Required.")
+	key := flag.String("key", "", "The Alma API key. You can manage your API keys here: https://developers.exlibrisgroup.com/manage/keys/. "+
+		"A key is required from this flag or one of keyfile, keycommand, keysecret, or keypass, which keep the key out of shell history and process listings.")
+	keyFile := flag.String("keyfile", "", "The path to a file holding the Alma API key.")
+	keyCommand := flag.String("keycommand", "", "A command which prints the Alma API key, like a password manager CLI.")
+	keySecret := flag.String("keysecret", "", "The name of an Alma API key stored in the Secret Service with 'secret-tool store --label=almatoolkit "+
+		keysource.SecretServiceAttribute+" NAME'.")
+	keyPass := flag.String("keypass", "", "The name of an Alma API key stored with the pass password manager.")
 	host := flag.String("host", api.DefaultAlmaAPIHost, "The Alma API host domain name to use.")
 	threshold := flag.Int("threshold", api.DefaultThreshold, "The minimum number of API calls remaining before the tool automatically stops working.")
+	callsPerSecond := flag.Int("rate", api.LimiterRate, "The maximum number of API calls per second.")
+	workers := flag.Int("workers", 0, "The number of concurrent workers making API calls. Defaults to the number of CPUs.")
+	share := flag.Bool("shared", false, "Share the rate limit and the remaining API calls with other toolkit processes on this computer, "+
+		"so together they make at most rate calls per second.")
+	sharedFile := flag.String("sharedfile", "", "The path to the state file used with the shared flag, like /srv/almatoolkit/state.json. Required with the shared flag.\n"+
+		"Everyone sharing the rate limit must use the same path, in a directory they can all write to.")
+	profileName := flag.String("profile", "", "The name of a profile from the config file to use for unset flags.")
+	config := flag.String("config", "", "The path to the profiles config file. Defaults to almatoolkit/"+profile.ConfigFileName+" in the user's config directory.")
+	estimate := flag.Bool("estimate", false, "Print an estimate of the number of API calls the subcommand will make, then exit.")
+	yesProduction := flag.Bool("yesproduction", false, "Run subcommands which make changes with a production profile without asking for confirmation.\n"+
+		"Only read from the command line, never from an environment variable.")
 	printVersion := flag.Bool("version", false, "Print the version then exit.")
 	printHelp := flag.Bool("help", false, "Print help documentation then exit.")
 
 	// Subcommands this tool understands.
 	registry := subcommand.Registry{}
+	registry.Register(keycheck.Config(EnvPrefix))
 	registry.Register(dump.Config(EnvPrefix))
+	registry.Register(diff.Config(EnvPrefix))
+	registry.Register(tableapply.Config(EnvPrefix))
 	registry.Register(cleanupcallnumbers.Config(EnvPrefix))
 	registry.Register(requests.Config(EnvPrefix))
 	registry.Register(cancelrequests.Config(EnvPrefix))
 	registry.Register(scanin.Config(EnvPrefix))
+	registry.Register(createrequest.Config(EnvPrefix))
+	registry.Register(locationreport.Config(EnvPrefix))
+	registry.Register(polreport.Config(EnvPrefix))
+	registry.Register(receive.Config(EnvPrefix))
+	registry.Register(vendorsreport.Config(EnvPrefix))
+	registry.Register(fundsreport.Config(EnvPrefix))
+	registry.Register(invoiceimport.Config(EnvPrefix))
+	registry.Register(portfoliosupdate.Config(EnvPrefix))
+	registry.Register(linkcheck.Config(EnvPrefix))
+	registry.Register(export.Config(EnvPrefix))
+	registry.Register(run.Config(EnvPrefix))
+	registry.Register(importjob.Config(EnvPrefix))
 
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "%v\n", ProjectName)
@@ -65,6 +115,9 @@ func main() {
 		flag.PrintDefaults()
 		fmt.Fprintln(flag.CommandLine.Output(), "  Environment variables read when flag is unset:")
 		flag.VisitAll(func(f *flag.Flag) {
+			if f.Name == "yesproduction" {
+				return
+			}
 			fmt.Fprintf(flag.CommandLine.Output(), "  %v%v\n", EnvPrefix, strings.ToUpper(f.Name))
 		})
 		fmt.Fprintln(flag.CommandLine.Output(), "")
@@ -90,15 +143,55 @@ func main() {
 	}
 
 	// If any flags have not been set, see if there are
-	// environment variables that set them.
+	// environment variables that set them. Skipping the production
+	// confirmation has to be asked for on the command line each time,
+	// so its value from the command line is kept.
+	yesProductionGiven := *yesProduction
 	err := overridefromenv.Override(flag.CommandLine, EnvPrefix)
 	if err != nil {
 		log.Fatalf("FATAL: %v.\n", err)
 	}
+	*yesProduction = yesProductionGiven
+
+	// Flags still unset are set from the profile, if one was chosen.
+	var prof profile.Profile
+	if *profileName != "" {
+		if *config == "" {
+			*config = profile.DefaultPath()
+		}
+		profiles, err := profile.Load(*config)
+		if err != nil {
+			log.Fatalf("FATAL: %v.\n", err)
+		}
+		prof, err = profiles.Profile(*profileName)
+		if err != nil {
+			log.Fatalf("FATAL: %v.\n", err)
+		}
+		err = prof.Apply(flag.CommandLine)
+		if err != nil {
+			log.Fatalf("FATAL: Applying profile %v failed, %v.\n", *profileName, err)
+		}
+	}
+
+	// Read the key from whichever source was set.
+	sources := keysource.Sources{
+		Flag:          *key,
+		File:          *keyFile,
+		Command:       *keyCommand,
+		SecretService: *keySecret,
+		Pass:          *keyPass,
+	}
+	*key, err = sources.Key()
+	if err != nil {
+		log.Fatalf("FATAL: %v.\n", err)
+	}
 
 	// Check that required flags are set.
-	if *key == "" {
-		log.Fatalln("FATAL: An Alma API key is required.")
+	if *callsPerSecond < 1 {
+		log.Fatalln("FATAL: The rate must be at least one API call per second.")
+	}
+	if *share && *sharedFile == "" {
+		log.Fatalln("FATAL: The shared flag requires a state file path, set with the sharedfile flag.")
 	}
 
 	// Was a subcommand provided? Was it valid?
@@ -125,6 +218,21 @@ func main() {
 			log.Fatalf("FATAL: %v.\n", err)
 		}
 	}
+	// Some flags need more permissions, like reading the members of a set from an Analytics report.
+	if sub.ExtraCapabilities != nil {
+		sub.Capabilities = append(sub.Capabilities, sub.ExtraCapabilities()...)
+	}
+
+	// Subcommands which make changes need confirmation when a production profile is used, unless they are dry runs.
+	if prof.Production && sub.Writes() && !*yesProduction && !*estimate {
+		dryrun := sub.FlagSet.Lookup("dryrun")
+		if dryrun == nil || dryrun.Value.String() != "true" {
+			err = profile.Confirm(os.Stdin, os.Stderr, *profileName, subName)
+			if err != nil {
+				log.Fatalf("FATAL: %v.\n", err)
+			}
+		}
+	}
 
 	// Keep track of child goroutines.
 	var wg sync.WaitGroup
@@ -147,18 +255,66 @@ func main() {
 	}()
 
 	// Initialize the API client.
-	c := api.NewClient(*host, *key, *threshold)
+	c := api.NewClient(*host, *key, *threshold, *callsPerSecond, *workers)
+	if *share {
+		l, err := shared.NewLimiter(*sharedFile, *callsPerSecond)
+		if err != nil {
+			cancel()
+			wg.Wait()
+			log.Fatalf("FATAL: Sharing the rate limit failed, %v.\n", err)
+		}
+		c.UseShared(l)
+	}
 
-	// Ensure the provided key can access the API endpoints it needs to for the requested subcommand.
-	err = c.CheckAPIandKey(ctx, sub.ReadAccess, sub.WriteAccess)
+	// Ensure the provided key has the permissions it needs for the requested subcommand.
+	err = c.CheckCapabilities(ctx, sub.Capabilities)
 	if err != nil {
 		cancel()
 		wg.Wait()
 		log.Fatalf("FATAL: API access check failed, %v.\n", err)
 	}
 
+	// Ensure the codes provided in the subcommand's flags are configured in Alma, before anything is changed.
+	err = subcommand.ValidateCodes(ctx, c, sub.CodeChecks)
+	if err != nil {
+		cancel()
+		wg.Wait()
+		log.Fatalf("FATAL: %v.\n", err)
+	}
+
+	// Estimate the number of API calls the subcommand will make, and refuse to run it
+	// if the calls would take the remaining calls for the day below the threshold.
+	if sub.Estimate != nil {
+		calls, err := sub.Estimate(ctx, c)
+		if err != nil {
+			cancel()
+			wg.Wait()
+			log.Fatalf("FATAL: Estimating API calls failed, %v.\n", err)
+		}
+		remaining, found := c.Usage.LastRemaining()
+		if found {
+			log.Printf("%v is estimated to make %v API call(s). %v call(s) remain for the day, the threshold is %v.\n",
+				subName, calls, remaining, *threshold)
+			if remaining-calls <= *threshold {
+				cancel()
+				wg.Wait()
+				log.Fatalf("FATAL: Running %v would take the remaining API calls below the threshold.\n", subName)
+			}
+		} else {
+			log.Printf("%v is estimated to make %v API call(s).\n", subName, calls)
+		}
+	} else if *estimate {
+		log.Printf("%v does not support estimating API calls.\n", subName)
+	}
+	if *estimate {
+		cancel()
+		wg.Wait()
+		os.Exit(0)
+	}
+
 	// Run the subcommand.
 	err = sub.Run(ctx, c)
+	c.Usage.Summary(os.Stderr)
 	if err != nil {
 		cancel()
 		wg.Wait()
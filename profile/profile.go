@@ -0,0 +1,187 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package profile provides named sets of settings for the Alma environments the toolkit runs against.
+package profile
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileName is the name of the profiles file in the user's configuration directory.
+const ConfigFileName = "profiles.yaml"
+
+// RegionHosts maps Alma API regions to their API host domain names.
+// https://developers.exlibrisgroup.com/alma/apis/#calling
+var RegionHosts = map[string]string{
+	"na":  "api-na.hosted.exlibrisgroup.com",
+	"eu":  "api-eu.hosted.exlibrisgroup.com",
+	"ap":  "api-ap.hosted.exlibrisgroup.com",
+	"aps": "api-aps.hosted.exlibrisgroup.com",
+	"ca":  "api-ca.hosted.exlibrisgroup.com",
+	"cn":  "api-cn.hosted.exlibrisgroup.com.cn",
+}
+
+// Profile stores the settings for one Alma environment, like a sandbox or production.
+type Profile struct {
+	// Host is the Alma API host domain name. It takes precedence over Region.
+	Host string `json:"host" yaml:"host"`
+	// Region is the Alma API region, like na or ca, used to find the host.
+	Region string `json:"region" yaml:"region"`
+	// KeyEnv is the name of the environment variable which holds the API key.
+	KeyEnv string `json:"key_env" yaml:"key_env"`
+	// KeyFile is the path to a file which holds the API key.
+	KeyFile string `json:"key_file" yaml:"key_file"`
+	// KeyCommand is a command which prints the API key.
+	KeyCommand string `json:"key_command" yaml:"key_command"`
+	// KeySecret is the name of the API key in the Secret Service.
+	KeySecret string `json:"key_secret" yaml:"key_secret"`
+	// KeyPass is the name of the API key in the pass password manager.
+	KeyPass string `json:"key_pass" yaml:"key_pass"`
+	// Threshold is the minimum number of API calls remaining before the tool stops working.
+	Threshold int `json:"threshold" yaml:"threshold"`
+	// Rate is the maximum number of API calls per second.
+	Rate int `json:"rate" yaml:"rate"`
+	// Workers is the number of concurrent workers making API calls.
+	Workers int `json:"workers" yaml:"workers"`
+	// Production profiles require confirmation before running subcommands which make changes.
+	Production bool `json:"production" yaml:"production"`
+}
+
+// Profiles maps profile names to profiles.
+type Profiles map[string]Profile
+
+// DefaultPath returns the path of the profiles file in the user's configuration directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ConfigFileName
+	}
+	return filepath.Join(dir, "almatoolkit", ConfigFileName)
+}
+
+// Load reads profiles from a YAML or JSON file. Files ending in .json are read as JSON.
+func Load(path string) (profiles Profiles, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return profiles, err
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &profiles)
+	} else {
+		err = yaml.UnmarshalStrict(data, &profiles)
+	}
+	if err != nil {
+		return profiles, fmt.Errorf("reading profiles from %v failed: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// Profile returns the named profile, or an error listing the profiles which are available.
+func (p Profiles) Profile(name string) (profile Profile, err error) {
+	profile, found := p[name]
+	if !found {
+		names := []string{}
+		for name := range p {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return profile, fmt.Errorf("no profile named '%v' found, the available profiles are: %v", name, strings.Join(names, ", "))
+	}
+	return profile, nil
+}
+
+// APIHost returns the host of the profile, or the host of its region.
+// An empty string is returned if neither is set.
+func (p Profile) APIHost() (host string, err error) {
+	if p.Host != "" || p.Region == "" {
+		return p.Host, nil
+	}
+	host, found := RegionHosts[strings.ToLower(p.Region)]
+	if !found {
+		return host, fmt.Errorf("'%v' is not a known Alma API region", p.Region)
+	}
+	return host, nil
+}
+
+// KeyFlags are the names of the flags which provide the API key.
+var KeyFlags = []string{"key", "keyfile", "keycommand", "keysecret", "keypass"}
+
+// Apply sets the flags in fs which were not set on the command line or by environment variables
+// to the values in the profile. The flags are host, threshold, rate, workers, and the KeyFlags.
+// If any of the KeyFlags were set, the key settings in the profile are not used.
+func (p Profile) Apply(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	host, err := p.APIHost()
+	if err != nil {
+		return err
+	}
+	values := map[string]string{"host": host}
+	keySet := false
+	for _, name := range KeyFlags {
+		keySet = keySet || set[name]
+	}
+	if !keySet {
+		if p.KeyEnv != "" {
+			key, found := os.LookupEnv(p.KeyEnv)
+			if !found {
+				return fmt.Errorf("the environment variable %v named in the profile is not set", p.KeyEnv)
+			}
+			values["key"] = key
+		}
+		values["keyfile"] = p.KeyFile
+		values["keycommand"] = p.KeyCommand
+		values["keysecret"] = p.KeySecret
+		values["keypass"] = p.KeyPass
+	}
+	if p.Threshold != 0 {
+		values["threshold"] = strconv.Itoa(p.Threshold)
+	}
+	if p.Rate != 0 {
+		values["rate"] = strconv.Itoa(p.Rate)
+	}
+	if p.Workers != 0 {
+		values["workers"] = strconv.Itoa(p.Workers)
+	}
+	for name, value := range values {
+		if set[name] || value == "" || fs.Lookup(name) == nil {
+			continue
+		}
+		err := fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("setting %v from the profile failed: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Confirm asks the user to type the profile name before changes are made with a production profile.
+func Confirm(in io.Reader, out io.Writer, name, subName string) error {
+	fmt.Fprintf(out, "WARNING: '%v' is a production profile, and %v makes changes in Alma.\n", name, subName)
+	fmt.Fprintf(out, "Type the name of the profile to continue: ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(answer) != name {
+		return fmt.Errorf("running %v with the production profile '%v' was not confirmed", subName, name)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package profile
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoad checks that profiles are read from YAML files.
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ConfigFileName)
+	config := "sandbox:\n  region: na\n  key_env: SANDBOX_KEY\n  rate: 5\n" +
+		"production:\n  host: api-ca.hosted.exlibrisgroup.com\n  production: true\n"
+	err = ioutil.WriteFile(path, []byte(config), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sandbox, err := profiles.Profile("sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sandbox.Region != "na" || sandbox.KeyEnv != "SANDBOX_KEY" || sandbox.Rate != 5 || sandbox.Production {
+		t.Fatalf("unexpected sandbox profile: %+v", sandbox)
+	}
+	production, err := profiles.Profile("production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !production.Production {
+		t.Fatal("production profile not marked as production")
+	}
+	_, err = profiles.Profile("staging")
+	if err == nil || !strings.Contains(err.Error(), "production, sandbox") {
+		t.Fatalf("missing profile error should list available profiles, got %v", err)
+	}
+}
+
+// TestApply checks that profiles only set flags which were not already set.
+func TestApply(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	host := fs.String("host", "default-host", "")
+	threshold := fs.Int("threshold", 50000, "")
+	rate := fs.Int("rate", 15, "")
+	key := fs.String("key", "", "")
+	err := fs.Parse([]string{"-threshold", "100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("ALMATOOLKIT_TEST_PROFILE_KEY", "secret")
+	defer os.Unsetenv("ALMATOOLKIT_TEST_PROFILE_KEY")
+	p := Profile{Region: "eu", KeyEnv: "ALMATOOLKIT_TEST_PROFILE_KEY", Threshold: 20000, Rate: 5}
+	err = p.Apply(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *host != RegionHosts["eu"] {
+		t.Errorf("host not set from region, got %v", *host)
+	}
+	if *threshold != 100 {
+		t.Errorf("threshold set on the command line was overridden, got %v", *threshold)
+	}
+	if *rate != 5 {
+		t.Errorf("rate not set from profile, got %v", *rate)
+	}
+	if *key != "secret" {
+		t.Errorf("key not set from environment variable, got %v", *key)
+	}
+}
+
+// TestConfirm checks that only the profile name confirms a production run.
+func TestConfirm(t *testing.T) {
+	err := Confirm(strings.NewReader("production\n"), ioutil.Discard, "production", "items-scan-in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Confirm(strings.NewReader("yes\n"), ioutil.Discard, "production", "items-scan-in")
+	if err == nil {
+		t.Fatal("an answer other than the profile name confirmed the run")
+	}
+}
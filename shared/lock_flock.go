@@ -0,0 +1,26 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package shared
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock takes an exclusive advisory lock on the file, blocking until it is available.
+// The lock is released when the returned function is called, or when the process exits.
+func lock(f *os.File) (unlock func(), err error) {
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
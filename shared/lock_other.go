@@ -0,0 +1,41 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package shared
+
+import (
+	"os"
+	"time"
+)
+
+// staleLock is how old a lock file can be before it is assumed its process died while holding it.
+const staleLock = 10 * time.Second
+
+// lock takes an exclusive lock on the file by creating a lock file next to it, blocking until it is available.
+// The lock is released when the returned function is called.
+func lock(f *os.File) (unlock func(), err error) {
+	path := f.Name() + ".lock"
+	for {
+		l, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_ = l.Close()
+			return func() {
+				_ = os.Remove(path)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err == nil && time.Since(info.ModTime()) > staleLock {
+			_ = os.Remove(path)
+			continue
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
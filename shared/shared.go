@@ -0,0 +1,165 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package shared coordinates the API calls made by toolkit processes running on the same computer.
+// The processes share one rate limit and one view of the remaining API calls for the day,
+// using a state file which is locked while it is read and written.
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// RemainingMaxAge is how long a count of the remaining API calls is trusted by other processes.
+const RemainingMaxAge = 10 * time.Minute
+
+// FileMode is the permissions of a new state file. Every user sharing the rate limit needs to read and write it,
+// so the permissions don't depend on the umask of the user who runs the toolkit first.
+const FileMode os.FileMode = 0666
+
+// state is stored in the state file.
+type state struct {
+	// Next is the time the next API call can be made, by any process.
+	Next time.Time `json:"next"`
+	// Remaining is the most recent number of API calls remaining for the day.
+	Remaining int `json:"remaining"`
+	// RemainingAt is when Remaining was recorded.
+	RemainingAt time.Time `json:"remaining_at"`
+}
+
+// Limiter is a rate limiter shared by every process using the same state file.
+type Limiter struct {
+	// Path is the path to the state file.
+	Path string
+	// interval is the time between API calls.
+	interval time.Duration
+}
+
+// NewLimiter returns a Limiter which allows callsPerSecond API calls per second across all processes using the state file.
+func NewLimiter(path string, callsPerSecond int) (*Limiter, error) {
+	if callsPerSecond < 1 {
+		return nil, fmt.Errorf("the rate must be at least one API call per second")
+	}
+	l := &Limiter{
+		Path:     path,
+		interval: time.Second / time.Duration(callsPerSecond),
+	}
+	// Ensure the state file can be created and locked.
+	err := l.update(func(s *state) {})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Wait blocks until an API call can be made, or the context is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	var slot time.Time
+	err := l.update(func(s *state) {
+		now := time.Now()
+		slot = s.Next
+		if slot.Before(now) {
+			slot = now
+		}
+		s.Next = slot.Add(l.interval)
+	})
+	if err != nil {
+		return err
+	}
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Record stores the number of API calls remaining, so other processes can see it.
+func (l *Limiter) Record(remaining int) error {
+	return l.update(func(s *state) {
+		s.Remaining = remaining
+		s.RemainingAt = time.Now()
+	})
+}
+
+// Remaining returns the most recent number of API calls remaining recorded by any process,
+// and whether one was recorded in the last RemainingMaxAge.
+func (l *Limiter) Remaining() (remaining int, found bool, err error) {
+	err = l.update(func(s *state) {
+		if !s.RemainingAt.IsZero() && time.Since(s.RemainingAt) < RemainingMaxAge {
+			remaining, found = s.Remaining, true
+		}
+	})
+	return remaining, found, err
+}
+
+// open opens the state file for reading and writing, creating it with FileMode if it doesn't exist.
+func (l *Limiter) open() (f *os.File, err error) {
+	f, err = os.OpenFile(l.Path, os.O_RDWR, 0)
+	if !os.IsNotExist(err) {
+		return f, err
+	}
+	f, err = os.OpenFile(l.Path, os.O_RDWR|os.O_CREATE|os.O_EXCL, FileMode)
+	if os.IsExist(err) {
+		// Another process created the file first.
+		return os.OpenFile(l.Path, os.O_RDWR, 0)
+	}
+	if err != nil {
+		return f, err
+	}
+	// The umask has removed some of the permissions, add them back.
+	err = f.Chmod(FileMode)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// update locks the state file, reads the state, calls change, then writes the state and unlocks the file.
+func (l *Limiter) update(change func(*state)) error {
+	f, err := l.open()
+	if os.IsPermission(err) {
+		return fmt.Errorf("opening the shared state file failed, every user sharing it needs permission to read and write it: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("opening the shared state file failed: %w", err)
+	}
+	defer f.Close()
+	unlock, err := lock(f)
+	if err != nil {
+		return fmt.Errorf("locking the shared state file failed: %w", err)
+	}
+	defer unlock()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("reading the shared state file failed: %w", err)
+	}
+	s := state{}
+	if len(data) != 0 {
+		// A damaged state file is replaced.
+		_ = json.Unmarshal(data, &s)
+	}
+	change(&s)
+	data, err = json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt(data, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("writing the shared state file failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package shared
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// secondUserEnv holds the path of the state file when the test binary is run as a second user.
+const secondUserEnv = "ALMATOOLKIT_SHARED_TEST_PATH"
+
+// nobody is the user ID of the second user.
+const nobody = 65534
+
+// TestFileMode checks that a new state file can be read and written by every user, whatever the umask.
+func TestFileMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	path := filepath.Join(dir, "state.json")
+	_, err = NewLimiter(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != FileMode {
+		t.Fatalf("expected the state file to have permissions %v, got %v", FileMode, info.Mode().Perm())
+	}
+}
+
+// TestSecondUser checks that a second user can share a state file created by the first user.
+// The test binary is run again as the nobody user, so the test needs to run as root.
+func TestSecondUser(t *testing.T) {
+	if os.Getenv(secondUserEnv) != "" || os.Geteuid() != 0 {
+		t.Skip("running as a second user needs root")
+	}
+	dir, err := ioutil.TempDir("", "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.Chmod(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "state.json")
+	l, err := NewLimiter(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test binary is copied, since the directory it was built in is only readable by its owner.
+	binary := filepath.Join(dir, "shared.test")
+	err = copyFile(os.Args[0], binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(binary, "-test.run=^TestSecondUserHelper$")
+	cmd.Env = append(os.Environ(), secondUserEnv+"="+path)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: nobody, Gid: nobody}}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("the second user couldn't use the state file: %v\n%s", err, output)
+	}
+	remaining, found, err := l.Remaining()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || remaining != 54321 {
+		t.Fatalf("the remaining calls recorded by the second user were not seen, got %v", remaining)
+	}
+}
+
+// TestSecondUserHelper records the remaining calls in the state file, when run by TestSecondUser.
+func TestSecondUserHelper(t *testing.T) {
+	path := os.Getenv(secondUserEnv)
+	if path == "" {
+		t.Skip("only run by TestSecondUser")
+	}
+	if os.Geteuid() != nobody {
+		t.Fatalf("expected to run as user %v, not %v", nobody, os.Geteuid())
+	}
+	l, err := NewLimiter(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Record(54321)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// copyFile copies the executable at the path to a new executable.
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
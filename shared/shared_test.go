@@ -0,0 +1,103 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package shared coordinates the API calls made by toolkit processes running on the same computer.
+// The processes share one rate limit and one view of the remaining API calls for the day,
+// using a state file which is locked while it is read and written.
+package shared
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLimiters returns two limiters sharing a state file, like two processes would.
+func testLimiters(t *testing.T, callsPerSecond int) (*Limiter, *Limiter, func()) {
+	dir, err := ioutil.TempDir("", "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "state.json")
+	a, err := NewLimiter(path, callsPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewLimiter(path, callsPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b, func() { os.RemoveAll(dir) }
+}
+
+// TestWaitShared checks that limiters sharing a state file share one rate.
+func TestWaitShared(t *testing.T) {
+	a, b, cleanup := testLimiters(t, 50)
+	defer cleanup()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, l := range []*Limiter{a, b} {
+		l := l
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := l.Wait(context.Background())
+				if err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	// 40 calls at 50 calls per second, the first without waiting.
+	if elapsed := time.Since(start); elapsed < 39*20*time.Millisecond {
+		t.Fatalf("40 calls took %v, the limiters did not share the rate", elapsed)
+	}
+}
+
+// TestWaitCancelled checks that Wait returns when the context is cancelled.
+func TestWaitCancelled(t *testing.T) {
+	a, _, cleanup := testLimiters(t, 1)
+	defer cleanup()
+	err := a.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = a.Wait(ctx)
+	if err == nil {
+		t.Fatal("expected an error from a cancelled context")
+	}
+}
+
+// TestRemainingShared checks that the remaining calls recorded by one limiter are seen by another.
+func TestRemainingShared(t *testing.T) {
+	a, b, cleanup := testLimiters(t, 10)
+	defer cleanup()
+	_, found, err := b.Remaining()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Fatal("expected no remaining calls to be recorded yet")
+	}
+	err = a.Record(12345)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining, found, err := b.Remaining()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || remaining != 12345 {
+		t.Fatalf("unexpected remaining calls %v", remaining)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package fundsreport provides a subcommand which reports fund balances.
+package fundsreport
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("acq-funds-report", flag.ExitOnError)
+	fiscalPeriod := fs.String("fiscalperiod", "", "The fiscal period to report on, like 2021. Defaults to the current fiscal period.")
+	status := fs.String("status", "ACTIVE", "The fund status to report on, ACTIVE, INACTIVE, or ALL.")
+	entityType := fs.String("entitytype", "ALL", "The kind of funds to report on, FUND, LEDGER, SUMMARY, or ALL.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	fs.Usage = func() {
+		description := "Report the allocated, encumbered, expended, and available balances of funds and ledgers\n" +
+			"for a fiscal period."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.AcqRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			return report.ValidateFormat(*format)
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			funds, errs := c.Funds(ctx, api.FundsQuery{Status: *status, FiscalPeriod: *fiscalPeriod, EntityType: *entityType})
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving funds", len(errs))
+			}
+			sort.Slice(funds, func(i, j int) bool {
+				if funds[i].Ledger.Value != funds[j].Ledger.Value {
+					return funds[i].Ledger.Value < funds[j].Ledger.Value
+				}
+				return funds[i].Code < funds[j].Code
+			})
+			w, err := report.NewWriter(os.Stdout, *format, []string{"Code", "Name", "Type", "Status", "Fiscal Period", "Ledger", "Parent Fund",
+				"Currency", "Allocated", "Encumbered", "Expended", "Available", "Cash"})
+			if err != nil {
+				return err
+			}
+			for _, fund := range funds {
+				err := w.Write([]string{fund.Code, fund.Name, fund.Type.Value, fund.Status.Value, fund.FiscalPeriod.Desc, fund.Ledger.Value,
+					fund.ParentFund.Value, fund.Currency.Value, string(fund.AllocatedBalance.Sum), string(fund.EncumberedBalance.Sum),
+					string(fund.ExpendedBalance.Sum), string(fund.AvailableBalance.Sum), string(fund.CashBalance.Sum)})
+				if err != nil {
+					return err
+				}
+			}
+			return w.Close()
+		},
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package invoiceimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The fields of an invoice line which can be mapped to columns of a vendor invoice CSV.
+const (
+	FieldInvoiceNumber         = "invoice_number"
+	FieldInvoiceDate           = "invoice_date"
+	FieldVendorReferenceNumber = "vendor_reference_number"
+	FieldQuantity              = "quantity"
+	FieldPrice                 = "price"
+	FieldTotalPrice            = "total_price"
+	FieldNote                  = "note"
+)
+
+// Fields are the fields which can be mapped, in the order they are documented.
+var Fields = []string{FieldInvoiceNumber, FieldInvoiceDate, FieldVendorReferenceNumber, FieldQuantity, FieldPrice, FieldTotalPrice, FieldNote}
+
+// RequiredFields are the fields every mapping must include.
+var RequiredFields = []string{FieldInvoiceNumber, FieldVendorReferenceNumber, FieldPrice}
+
+// Mapping maps fields to the names of the vendor invoice CSV columns which hold them.
+type Mapping map[string]string
+
+// ReadMapping reads a mapping from a CSV with a header row and two columns, the field and the column name.
+func ReadMapping(r io.Reader) (mapping Mapping, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return mapping, fmt.Errorf("reading the mapping csv failed: %w", err)
+	}
+	if len(records) == 0 {
+		return mapping, fmt.Errorf("the mapping csv is empty")
+	}
+	known := map[string]bool{}
+	for _, field := range Fields {
+		known[field] = true
+	}
+	mapping = Mapping{}
+	// The first record is the header.
+	for _, record := range records[1:] {
+		field := strings.ToLower(strings.TrimSpace(record[0]))
+		if !known[field] {
+			return mapping, fmt.Errorf("'%v' is not a field which can be mapped, the fields are %v", record[0], strings.Join(Fields, ", "))
+		}
+		if _, found := mapping[field]; found {
+			return mapping, fmt.Errorf("the field '%v' is mapped more than once", field)
+		}
+		mapping[field] = strings.TrimSpace(record[1])
+	}
+	for _, field := range RequiredFields {
+		if mapping[field] == "" {
+			return mapping, fmt.Errorf("the mapping must include the '%v' field", field)
+		}
+	}
+	return mapping, nil
+}
+
+// Line is a line of a vendor invoice CSV.
+type Line struct {
+	// Row is the row of the CSV the line was read from, counting the header as row 1.
+	Row                   int
+	InvoiceNumber         string
+	InvoiceDate           string // Formatted like 2006-01-02, or empty.
+	VendorReferenceNumber string
+	Quantity              int
+	Price                 string
+	TotalPrice            string
+	Note                  string
+}
+
+// ReadLines reads the lines of a vendor invoice CSV with a header row, using the mapping to find the columns.
+// Column names are matched without regard to case. Invoice dates are parsed with the date format, like 01/02/2006.
+// The quantity defaults to 1, and the total price defaults to the price times the quantity.
+func ReadLines(r io.Reader, mapping Mapping, dateFormat string) (lines []Line, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return lines, fmt.Errorf("reading the csv header failed: %w", err)
+	}
+	indexes := map[string]int{}
+	for field, column := range mapping {
+		indexes[field] = -1
+		for i, name := range header {
+			// Spreadsheet programs often start CSV files with a byte order mark.
+			name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+			if strings.EqualFold(name, column) {
+				indexes[field] = i
+			}
+		}
+		if indexes[field] == -1 {
+			return lines, fmt.Errorf("the csv has no '%v' column for the %v field", column, field)
+		}
+	}
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, fmt.Errorf("reading the csv failed: %w", err)
+		}
+		value := func(field string) string {
+			i, found := indexes[field]
+			if !found || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		line := Line{
+			Row:                   row,
+			InvoiceNumber:         value(FieldInvoiceNumber),
+			VendorReferenceNumber: value(FieldVendorReferenceNumber),
+			Quantity:              1,
+			Note:                  value(FieldNote),
+		}
+		if line.InvoiceNumber == "" || line.VendorReferenceNumber == "" {
+			return lines, fmt.Errorf("row %v of the csv has no invoice number or vendor reference number", row)
+		}
+		if date := value(FieldInvoiceDate); date != "" {
+			parsed, err := time.Parse(dateFormat, date)
+			if err != nil {
+				return lines, fmt.Errorf("the invoice date '%v' on row %v does not match the date format %v", date, row, dateFormat)
+			}
+			line.InvoiceDate = parsed.Format("2006-01-02")
+		}
+		if quantity := value(FieldQuantity); quantity != "" {
+			line.Quantity, err = strconv.Atoi(quantity)
+			if err != nil || line.Quantity < 1 {
+				return lines, fmt.Errorf("the quantity '%v' on row %v is not a positive whole number", quantity, row)
+			}
+		}
+		price, err := parseAmount(value(FieldPrice))
+		if err != nil {
+			return lines, fmt.Errorf("the price on row %v is invalid: %w", row, err)
+		}
+		line.Price = price.FloatString(2)
+		total := new(big.Rat).Mul(price, new(big.Rat).SetInt64(int64(line.Quantity)))
+		if totalPrice := value(FieldTotalPrice); totalPrice != "" {
+			total, err = parseAmount(totalPrice)
+			if err != nil {
+				return lines, fmt.Errorf("the total price on row %v is invalid: %w", row, err)
+			}
+		}
+		line.TotalPrice = total.FloatString(2)
+		lines = append(lines, line)
+	}
+}
+
+// parseAmount parses an amount of money, like 1,024.50 or $12.
+func parseAmount(s string) (*big.Rat, error) {
+	cleaned := strings.NewReplacer(",", "", "$", "", " ", "").Replace(s)
+	amount, ok := new(big.Rat).SetString(cleaned)
+	if !ok {
+		return nil, fmt.Errorf("'%v' is not an amount", s)
+	}
+	return amount, nil
+}
+
+// VendorInvoice is an invoice in a vendor invoice CSV, made up of the lines with the same invoice number.
+type VendorInvoice struct {
+	Number string
+	// Date is the first invoice date of the lines.
+	Date  string
+	Lines []Line
+}
+
+// Total returns the sum of the total prices of the lines.
+func (v VendorInvoice) Total() string {
+	total := new(big.Rat)
+	for _, line := range v.Lines {
+		// The total price was formatted by ReadLines, so it always parses.
+		price, _ := new(big.Rat).SetString(line.TotalPrice)
+		total.Add(total, price)
+	}
+	return total.FloatString(2)
+}
+
+// GroupLines groups the lines by invoice number, in the order the invoice numbers first appear.
+func GroupLines(lines []Line) (invoices []VendorInvoice) {
+	indexes := map[string]int{}
+	for _, line := range lines {
+		i, found := indexes[line.InvoiceNumber]
+		if !found {
+			i = len(invoices)
+			indexes[line.InvoiceNumber] = i
+			invoices = append(invoices, VendorInvoice{Number: line.InvoiceNumber})
+		}
+		if invoices[i].Date == "" {
+			invoices[i].Date = line.InvoiceDate
+		}
+		invoices[i].Lines = append(invoices[i].Lines, line)
+	}
+	return invoices
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package invoiceimport
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMapping = "field,column\n" +
+	"invoice_number,Invoice No\n" +
+	"INVOICE_DATE, Date\n" +
+	"vendor_reference_number,Order Ref\n" +
+	"quantity,Qty\n" +
+	"price,Unit Price\n"
+
+// TestReadMapping checks that the fields are read, and that bad mappings are rejected.
+func TestReadMapping(t *testing.T) {
+	mapping, err := ReadMapping(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping[FieldInvoiceDate] != "Date" || mapping[FieldPrice] != "Unit Price" || len(mapping) != 5 {
+		t.Fatalf("unexpected mapping %v", mapping)
+	}
+	for _, input := range []string{
+		"",
+		"field,column\ninvoice_number,Invoice No\nprice,Unit Price\n",
+		testMapping + "isbn,ISBN\n",
+		testMapping + "price,Net Price\n",
+		"field,column\ninvoice_number\n",
+	} {
+		_, err := ReadMapping(strings.NewReader(input))
+		if err == nil {
+			t.Fatalf("expected an error for %q", input)
+		}
+	}
+}
+
+// TestReadLines checks that lines are read using the mapping, and grouped into invoices.
+func TestReadLines(t *testing.T) {
+	mapping, err := ReadMapping(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "\ufeffInvoice No,date,Order Ref,Title,Qty,Unit Price\n" +
+		"INV-1,03/15/2021,REF-1,A book,2,\"1,024.50\"\n" +
+		"INV-2,03/16/2021,REF-2,Another book,,$12\n" +
+		"INV-1,,REF-3,A third book,1,0.10\n"
+	lines, err := ReadLines(strings.NewReader(input), mapping, "01/02/2006")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Line{
+		{Row: 2, InvoiceNumber: "INV-1", InvoiceDate: "2021-03-15", VendorReferenceNumber: "REF-1", Quantity: 2, Price: "1024.50", TotalPrice: "2049.00"},
+		{Row: 3, InvoiceNumber: "INV-2", InvoiceDate: "2021-03-16", VendorReferenceNumber: "REF-2", Quantity: 1, Price: "12.00", TotalPrice: "12.00"},
+		{Row: 4, InvoiceNumber: "INV-1", VendorReferenceNumber: "REF-3", Quantity: 1, Price: "0.10", TotalPrice: "0.10"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v lines, got %v", len(expected), len(lines))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("unexpected line %+v", lines[i])
+		}
+	}
+	invoices := GroupLines(lines)
+	if len(invoices) != 2 || invoices[0].Number != "INV-1" || len(invoices[0].Lines) != 2 || invoices[0].Date != "2021-03-15" {
+		t.Fatalf("unexpected invoices %+v", invoices)
+	}
+	if invoices[0].Total() != "2049.10" {
+		t.Fatalf("unexpected total %v", invoices[0].Total())
+	}
+}
+
+// TestReadLinesErrors checks that lines with missing or invalid values are rejected.
+func TestReadLinesErrors(t *testing.T) {
+	mapping, err := ReadMapping(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "Invoice No,Date,Order Ref,Qty,Unit Price\n"
+	for _, input := range []string{
+		"Invoice No,Order Ref,Qty,Unit Price\nINV-1,REF-1,1,10\n",
+		header + "INV-1,2021-03-15,REF-1,1,10\n",
+		header + "INV-1,,,1,10\n",
+		header + "INV-1,,REF-1,0,10\n",
+		header + "INV-1,,REF-1,1,ten\n",
+	} {
+		_, err := ReadLines(strings.NewReader(input), mapping, "01/02/2006")
+		if err == nil {
+			t.Fatalf("expected an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package invoiceimport provides a subcommand which creates invoices from vendor invoice CSVs.
+package invoiceimport
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// The statuses of lines in the report. Incomplete invoices were created in Alma,
+// but one of their lines wasn't added or they weren't processed.
+const (
+	StatusMatched    = "Matched"
+	StatusUnmatched  = "Unmatched"
+	StatusAmbiguous  = "Ambiguous"
+	StatusSkipped    = "Skipped"
+	StatusCreated    = "Created"
+	StatusIncomplete = "Incomplete"
+	StatusFailed     = "Failed"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("acq-invoice-import", flag.ExitOnError)
+	file := fs.String("file", "", "The path to the vendor invoice CSV. Required.")
+	mapping := fs.String("mapping", "", "The path to a CSV which maps fields to the columns of the vendor invoice CSV. Required.\n"+
+		"The mapping has a header row, then one row per field, with the field and the column name. The fields are\n"+
+		"invoice_number, invoice_date, vendor_reference_number, quantity, price, total_price, and note.\n"+
+		"The invoice_number, vendor_reference_number, and price fields are required.")
+	dateFormat := fs.String("dateformat", "2006-01-02", "The format of the invoice dates in the vendor invoice CSV, written as the date January 2, 2006.")
+	vendor := fs.String("vendor", "", "The code of the vendor which sent the invoice. Required.")
+	account := fs.String("account", "", "The code of the vendor account the invoices are charged to.")
+	status := fs.String("status", "ACTIVE", "The status of the PO lines which lines are matched to, like ACTIVE or ALL.")
+	owner := fs.String("owner", "", "The code of the library which owns the invoices. Defaults to the institution.")
+	currency := fs.String("currency", "", "The currency code of the invoices, like CAD. Defaults to the currency of the first matched PO line.")
+	paymentMethod := fs.String("paymentmethod", api.DefaultPaymentMethod, "The payment method code of the invoices.")
+	process := fs.Bool("process", false, "Process the invoices after they are created, which approves them or sends them to review.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	dryrun := fs.Bool("dryrun", false, "Do not create any invoices. Report on how the lines match PO lines.")
+	fs.Usage = func() {
+		description := "Create invoices from a vendor invoice CSV. Lines are matched to PO lines from the vendor by their\n" +
+			"vendor reference numbers. Invoices with lines which don't match exactly one PO line are not created.\n" +
+			"Every line is reported with the PO line it matched, and whether it was created in Alma."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	config := &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.AcqWrite},
+		FlagSet:      fs,
+		CodeChecks: []subcommand.CodeCheck{
+			{Flag: "owner", Value: owner, Source: subcommand.LibraryCodes},
+		},
+	}
+	config.ValidateFlags = func() error {
+		if *file == "" || *mapping == "" {
+			return fmt.Errorf("a vendor invoice file and a mapping file are required")
+		}
+		if *vendor == "" {
+			return fmt.Errorf("a vendor code is required")
+		}
+		return report.ValidateFormat(*format)
+	}
+	config.Estimate = func(ctx context.Context, c *api.Client) (calls int, err error) {
+		invoices, err := readFiles(*file, *mapping, *dateFormat)
+		if err != nil {
+			return calls, err
+		}
+		// Getting the PO lines takes one call for every 100 lines, which can't be known before the first call.
+		calls = 1
+		for _, invoice := range invoices {
+			calls += 1 + len(invoice.Lines)
+			if *process {
+				calls++
+			}
+		}
+		return calls, nil
+	}
+	config.Run = func(ctx context.Context, c *api.Client) error {
+		if *dryrun {
+			log.Println("Running in dry run mode, no changes will be made in Alma.")
+		} else {
+			log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+		}
+		invoices, err := readFiles(*file, *mapping, *dateFormat)
+		if err != nil {
+			return err
+		}
+		// Only the vendor's PO lines are requested, Index still checks the vendor code since Alma's search matches words.
+		poLines, errs := c.POLines(ctx, *status, "vendor_code~"+*vendor)
+		if len(errs) != 0 {
+			for _, err := range errs {
+				log.Println(err)
+			}
+			return fmt.Errorf("%v error(s) occured when retrieving PO lines", len(errs))
+		}
+		index := Index(poLines, *vendor)
+		w, err := report.NewWriter(os.Stdout, *format, []string{"Invoice Number", "Row", "Vendor Reference Number", "Quantity", "Price",
+			"Total Price", "PO Line", "Title", "Status", "Alma Invoice ID"})
+		if err != nil {
+			return err
+		}
+		created, unmatched := 0, 0
+		// stopped is set when the API call threshold is reached, so no more invoices are created.
+		stopped := false
+		errs = []error{}
+		for _, invoice := range invoices {
+			matches := Match(invoice, index)
+			complete := true
+			for _, match := range matches {
+				if match.Status != StatusMatched {
+					complete = false
+					unmatched++
+				}
+			}
+			invoiceID := ""
+			var invoiceErr error
+			attempted := complete && !*dryrun && !stopped
+			if attempted {
+				template := api.Invoice{
+					VendorAccount: *account,
+					Currency:      api.Value{Value: *currency},
+					PaymentMethod: api.Value{Value: *paymentMethod},
+				}
+				// Without an owner, Alma makes the institution the owner.
+				if *owner != "" {
+					template.Owner = &api.Value{Value: *owner}
+				}
+				invoiceID, invoiceErr = createInvoice(ctx, c, template, *vendor, invoice, matches, *process)
+				if invoiceErr != nil {
+					errs = append(errs, invoiceErr)
+					var over *api.ThresholdReachedError
+					if errors.As(invoiceErr, &over) {
+						stopped = true
+					}
+				} else {
+					created++
+				}
+			}
+			for _, match := range matches {
+				status := match.Status
+				switch {
+				case !complete && status == StatusMatched:
+					status = StatusSkipped
+				case attempted && invoiceID != "" && invoiceErr != nil:
+					status = StatusIncomplete
+				case attempted && invoiceID != "":
+					status = StatusCreated
+				case attempted:
+					status = StatusFailed
+				}
+				err := w.Write([]string{invoice.Number, strconv.Itoa(match.Line.Row), match.Line.VendorReferenceNumber,
+					strconv.Itoa(match.Line.Quantity), match.Line.Price, match.Line.TotalPrice, match.POLine.Number,
+					match.POLine.ResourceMetadata.Title, status, invoiceID})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		err = w.Close()
+		if err != nil {
+			return err
+		}
+		log.Printf("%v of %v invoice(s) created, %v line(s) did not match exactly one PO line.\n", created, len(invoices), unmatched)
+		if len(errs) != 0 {
+			for _, err := range errs {
+				log.Println(err)
+			}
+			return fmt.Errorf("%v error(s) occured when creating invoices", len(errs))
+		}
+		return nil
+	}
+	return config
+}
+
+// readFiles reads the mapping, then the invoices from the vendor invoice CSV at the paths.
+func readFiles(path, mappingPath, dateFormat string) (invoices []VendorInvoice, err error) {
+	mf, err := os.Open(mappingPath)
+	if err != nil {
+		return invoices, err
+	}
+	defer mf.Close()
+	mapping, err := ReadMapping(mf)
+	if err != nil {
+		return invoices, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return invoices, err
+	}
+	defer f.Close()
+	lines, err := ReadLines(f, mapping, dateFormat)
+	if err != nil {
+		return invoices, err
+	}
+	return GroupLines(lines), nil
+}
+
+// Index returns the PO lines from the vendor, keyed by their vendor reference numbers.
+func Index(poLines []api.POLine, vendor string) map[string][]api.POLine {
+	index := map[string][]api.POLine{}
+	for _, poLine := range poLines {
+		if poLine.Vendor.Value == vendor && poLine.VendorReferenceNumber != "" {
+			index[poLine.VendorReferenceNumber] = append(index[poLine.VendorReferenceNumber], poLine)
+		}
+	}
+	return index
+}
+
+// LineMatch is a line of a vendor invoice and the PO line it matched.
+type LineMatch struct {
+	Line   Line
+	POLine api.POLine
+	// Status is StatusMatched, StatusUnmatched, or StatusAmbiguous.
+	Status string
+}
+
+// Match matches the lines of the invoice to PO lines in the index by their vendor reference numbers.
+func Match(invoice VendorInvoice, index map[string][]api.POLine) (matches []LineMatch) {
+	for _, line := range invoice.Lines {
+		match := LineMatch{Line: line, Status: StatusUnmatched}
+		poLines := index[line.VendorReferenceNumber]
+		if len(poLines) == 1 {
+			match.POLine = poLines[0]
+			match.Status = StatusMatched
+		} else if len(poLines) > 1 {
+			match.Status = StatusAmbiguous
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// Share returns the percent share of the total price, rounded to the cent.
+// The total price must be formatted by ReadLines. If the total price or the percent is not a number,
+// the total price is returned unchanged.
+func Share(totalPrice, percent string) string {
+	total, ok := new(big.Rat).SetString(totalPrice)
+	if !ok {
+		return totalPrice
+	}
+	p, ok := new(big.Rat).SetString(percent)
+	if !ok {
+		return totalPrice
+	}
+	return total.Mul(total, p.Quo(p, big.NewRat(100, 1))).FloatString(2)
+}
+
+// createInvoice creates the invoice and its lines in Alma, then processes it if process is true.
+// It returns the ID of the invoice if it was created, even if an error occured after it was created.
+func createInvoice(ctx context.Context, c *api.Client, template api.Invoice, vendor string, invoice VendorInvoice,
+	matches []LineMatch, process bool) (ID string, err error) {
+	template.Number = invoice.Number
+	template.Vendor = api.Value{Value: vendor}
+	template.TotalAmount = api.Decimal(invoice.Total())
+	date := invoice.Date
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	template.InvoiceDate = date + "Z"
+	if template.Currency.Value == "" {
+		template.Currency = matches[0].POLine.Price.Currency
+	}
+	created, err := c.InvoiceCreate(ctx, template)
+	if err != nil {
+		return ID, err
+	}
+	for _, match := range matches {
+		line := api.InvoiceLine{
+			Type:       api.Value{Value: "REGULAR"},
+			POLine:     match.POLine.Number,
+			Price:      api.Decimal(match.Line.Price),
+			Quantity:   match.Line.Quantity,
+			TotalPrice: api.Decimal(match.Line.TotalPrice),
+			Note:       match.Line.Note,
+		}
+		// The line is paid from the funds of the PO line, in the same proportions.
+		for _, distribution := range match.POLine.FundDistribution {
+			line.FundDistribution = append(line.FundDistribution, api.FundDistribution{
+				FundCode: distribution.FundCode,
+				Percent:  distribution.Percent,
+				Amount: api.Amount{
+					Sum:      api.Decimal(Share(match.Line.TotalPrice, string(distribution.Percent))),
+					Currency: template.Currency,
+				},
+			})
+		}
+		_, err := c.InvoiceLineCreate(ctx, created.ID, line)
+		if err != nil {
+			return created.ID, fmt.Errorf("invoice %v (ID %v) was created but is incomplete: %w", invoice.Number, created.ID, err)
+		}
+	}
+	if process {
+		_, err := c.InvoiceProcess(ctx, created.ID)
+		if err != nil {
+			return created.ID, fmt.Errorf("invoice %v (ID %v) was created but not processed: %w", invoice.Number, created.ID, err)
+		}
+	}
+	return created.ID, nil
+}
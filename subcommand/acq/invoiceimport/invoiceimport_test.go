@@ -0,0 +1,93 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package invoiceimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestMatch checks that lines only match a single PO line from the vendor.
+func TestMatch(t *testing.T) {
+	poLines := []api.POLine{
+		{Number: "POL-1", Vendor: api.Value{Value: "AMAZON"}, VendorReferenceNumber: "REF-1"},
+		{Number: "POL-2", Vendor: api.Value{Value: "AMAZON"}, VendorReferenceNumber: "REF-2"},
+		{Number: "POL-3", Vendor: api.Value{Value: "AMAZON"}, VendorReferenceNumber: "REF-2"},
+		{Number: "POL-4", Vendor: api.Value{Value: "OTHER"}, VendorReferenceNumber: "REF-3"},
+	}
+	invoice := VendorInvoice{Number: "INV-1", Lines: []Line{
+		{VendorReferenceNumber: "REF-1"},
+		{VendorReferenceNumber: "REF-2"},
+		{VendorReferenceNumber: "REF-3"},
+	}}
+	matches := Match(invoice, Index(poLines, "AMAZON"))
+	expected := []struct{ poLine, status string }{{"POL-1", StatusMatched}, {"", StatusAmbiguous}, {"", StatusUnmatched}}
+	for i, e := range expected {
+		if matches[i].POLine.Number != e.poLine || matches[i].Status != e.status {
+			t.Fatalf("unexpected match %+v", matches[i])
+		}
+	}
+}
+
+// TestShare checks that fund shares are rounded to the cent.
+func TestShare(t *testing.T) {
+	for _, test := range []struct{ total, percent, expected string }{
+		{"100.00", "60", "60.00"},
+		{"10.00", "33.333", "3.33"},
+		{"10.00", "", "10.00"},
+		{"", "60", ""},
+		{"ten", "60", "ten"},
+	} {
+		if share := Share(test.total, test.percent); share != test.expected {
+			t.Fatalf("expected %v of %v to be %v, got %v", test.percent, test.total, test.expected, share)
+		}
+	}
+}
+
+// TestCreateInvoiceIncomplete checks that the ID of the invoice is returned when a line can't be added to it,
+// and that no owner is sent when one isn't given.
+func TestCreateInvoiceIncomplete(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/almaws/v1/acq/invoices" {
+			invoice := map[string]interface{}{}
+			err := json.NewDecoder(r.Body).Decode(&invoice)
+			if err != nil {
+				t.Error(err)
+			}
+			if _, found := invoice["owner"]; found {
+				t.Errorf("an owner was sent, %v", invoice["owner"])
+			}
+			fmt.Fprint(w, `{"id":"INV-ID","number":"INV-1"}`)
+			return
+		}
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &api.Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+	invoice := VendorInvoice{Number: "INV-1", Lines: []Line{{VendorReferenceNumber: "REF-1", Quantity: 1, Price: "10.00", TotalPrice: "10.00"}}}
+	matches := []LineMatch{{Line: invoice.Lines[0], POLine: api.POLine{Number: "POL-1"}, Status: StatusMatched}}
+	ID, err := createInvoice(context.Background(), c, api.Invoice{Currency: api.Value{Value: "CAD"}}, "AMAZON", invoice, matches, false)
+	if err == nil {
+		t.Fatal("expected an error when the line can't be created")
+	}
+	if ID != "INV-ID" {
+		t.Fatalf("expected the ID of the created invoice, got %q", ID)
+	}
+}
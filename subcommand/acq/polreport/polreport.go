@@ -0,0 +1,126 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package polreport provides a subcommand which reports on purchase order lines.
+package polreport
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("pol-report", flag.ExitOnError)
+	status := fs.String("status", "ACTIVE", "The PO line status to report on, like ACTIVE, CLOSED, or ALL. See the Alma API documentation for the possible values.")
+	vendor := fs.String("vendor", "", "Only report on PO lines from the vendor with this code.")
+	fund := fs.String("fund", "", "Only report on PO lines paid at least in part from the fund with this code.")
+	overdue := fs.Bool("overdue", false, "Only report on PO lines with an expected receipt date before today, which may need to be claimed.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	fs.Usage = func() {
+		description := "Report on purchase order lines, filtered by status, vendor, or fund.\n" +
+			"The report includes the price, fund distribution, and expected receipt date of each line."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.AcqRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			return report.ValidateFormat(*format)
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			lines, errs := c.POLines(ctx, *status, Query(*vendor, *fund))
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving PO lines", len(errs))
+			}
+			today := time.Now().Format("2006-01-02")
+			filtered := []api.POLine{}
+			for _, line := range lines {
+				if Match(line, *vendor, *fund) && (!*overdue || line.Overdue(today)) {
+					filtered = append(filtered, line)
+				}
+			}
+			sort.Slice(filtered, func(i, j int) bool {
+				return filtered[i].Number < filtered[j].Number
+			})
+			w, err := report.NewWriter(os.Stdout, *format, []string{"PO Line", "PO Number", "Status", "Type", "Vendor", "Vendor Account",
+				"Vendor Reference Number", "Title", "MMS ID", "Price", "Currency", "Fund Distribution", "Expected Receipt Date",
+				"Claiming Interval", "Overdue"})
+			if err != nil {
+				return err
+			}
+			for _, line := range filtered {
+				overdue := "no"
+				if line.Overdue(today) {
+					overdue = "yes"
+				}
+				err := w.Write([]string{line.Number, line.PONumber, line.Status.Value, line.Type.Value, line.Vendor.Value, line.VendorAccount,
+					line.VendorReferenceNumber, line.ResourceMetadata.Title, line.ResourceMetadata.MMSID.Value,
+					string(line.Price.Sum), line.Price.Currency.Value, FundDistribution(line), line.ExpectedReceiptDate,
+					string(line.ClaimingInterval), overdue})
+				if err != nil {
+					return err
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				return err
+			}
+			log.Printf("%v of %v PO line(s) reported.\n", len(filtered), len(lines))
+			return nil
+		},
+	}
+}
+
+// Query returns the q parameter which asks Alma for the lines from the vendor and paid from the fund.
+// Alma's ~ operator matches words rather than whole codes, so the lines returned are still checked with Match.
+func Query(vendor, fund string) string {
+	conditions := []string{}
+	if vendor != "" {
+		conditions = append(conditions, "vendor_code~"+vendor)
+	}
+	if fund != "" {
+		conditions = append(conditions, "fund_code~"+fund)
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// Match returns true if the line is from the vendor and paid from the fund. Empty codes match every line.
+func Match(line api.POLine, vendor, fund string) bool {
+	if vendor != "" && line.Vendor.Value != vendor {
+		return false
+	}
+	if fund == "" {
+		return true
+	}
+	for _, distribution := range line.FundDistribution {
+		if distribution.FundCode.Value == fund {
+			return true
+		}
+	}
+	return false
+}
+
+// FundDistribution returns the funds a line is paid from, like "BOOKS (60%); SERIALS (40%)".
+func FundDistribution(line api.POLine) string {
+	funds := []string{}
+	for _, distribution := range line.FundDistribution {
+		funds = append(funds, fmt.Sprintf("%v (%v%%)", distribution.FundCode.Value, distribution.Percent))
+	}
+	return strings.Join(funds, "; ")
+}
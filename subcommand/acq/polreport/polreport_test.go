@@ -0,0 +1,28 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package polreport
+
+import (
+	"testing"
+)
+
+// TestQuery checks that the vendor and fund are combined into Alma's q parameter.
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		vendor, fund, want string
+	}{
+		{"", "", ""},
+		{"AMAZON", "", "vendor_code~AMAZON"},
+		{"", "BOOKS", "fund_code~BOOKS"},
+		{"AMAZON", "BOOKS", "vendor_code~AMAZON AND fund_code~BOOKS"},
+	}
+	for _, test := range tests {
+		got := Query(test.vendor, test.fund)
+		if got != test.want {
+			t.Errorf("Query(%q, %q) = %q, want %q", test.vendor, test.fund, got, test.want)
+		}
+	}
+}
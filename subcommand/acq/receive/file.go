@@ -0,0 +1,65 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package receive
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Receipt is a line of a vendor invoice CSV, naming an item to receive.
+type Receipt struct {
+	Barcode string
+	// POLine is optional. When it is empty, the PO line of the item in Alma is used.
+	POLine string
+}
+
+// ReadReceipts reads receipts from a CSV with a header row. The barcode column is required,
+// the PO line column is used if it is present. Column names are matched without regard to case.
+func ReadReceipts(r io.Reader, barcodeColumn, poLineColumn string) (receipts []Receipt, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return receipts, fmt.Errorf("reading the csv header failed: %w", err)
+	}
+	barcodeIndex, poLineIndex := -1, -1
+	for i, name := range header {
+		// Spreadsheet programs often start CSV files with a byte order mark.
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if strings.EqualFold(name, barcodeColumn) {
+			barcodeIndex = i
+		}
+		if strings.EqualFold(name, poLineColumn) {
+			poLineIndex = i
+		}
+	}
+	if barcodeIndex == -1 {
+		return receipts, fmt.Errorf("the csv has no '%v' column", barcodeColumn)
+	}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return receipts, nil
+		}
+		if err != nil {
+			return receipts, fmt.Errorf("reading the csv failed: %w", err)
+		}
+		receipt := Receipt{}
+		if barcodeIndex < len(record) {
+			receipt.Barcode = strings.TrimSpace(record[barcodeIndex])
+		}
+		if poLineIndex != -1 && poLineIndex < len(record) {
+			receipt.POLine = strings.TrimSpace(record[poLineIndex])
+		}
+		if receipt.Barcode == "" {
+			return receipts, fmt.Errorf("line %v of the csv has no barcode", line)
+		}
+		receipts = append(receipts, receipt)
+	}
+}
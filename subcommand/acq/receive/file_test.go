@@ -0,0 +1,41 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package receive
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestReadReceipts checks that barcodes and PO lines are read from the named columns.
+func TestReadReceipts(t *testing.T) {
+	input := "\ufeffInvoice,barcode,PO Line,Price\n" +
+		"INV-1, 39000000001 ,POL-1,10.00\n" +
+		"INV-1,39000000002,,12.00\n"
+	receipts, err := ReadReceipts(strings.NewReader(input), "Barcode", "PO Line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Receipt{{"39000000001", "POL-1"}, {"39000000002", ""}}
+	if len(receipts) != len(expected) {
+		t.Fatalf("expected %v receipts, got %v", len(expected), len(receipts))
+	}
+	for i := range expected {
+		if receipts[i] != expected[i] {
+			t.Fatalf("unexpected receipt %+v", receipts[i])
+		}
+	}
+}
+
+// TestReadReceiptsErrors checks that files without barcodes are rejected.
+func TestReadReceiptsErrors(t *testing.T) {
+	for _, input := range []string{"Title,PO Line\nA,POL-1\n", "Barcode,PO Line\n,POL-1\n", ""} {
+		_, err := ReadReceipts(strings.NewReader(input), "Barcode", "PO Line")
+		if err == nil {
+			t.Fatalf("expected an error for %q", input)
+		}
+	}
+}
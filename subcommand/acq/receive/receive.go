@@ -0,0 +1,200 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package receive provides a subcommand which receives items on purchase order lines.
+package receive
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("acq-receive", flag.ExitOnError)
+	source := subcommand.NewMemberSource(fs, subcommand.ItemContent)
+	file := fs.String("file", "", "The path to a vendor invoice CSV with a barcode column. Used instead of a set or Analytics report.")
+	barcodeColumn := fs.String("barcodecolumn", "Barcode", "The name of the barcode column in the file.")
+	poLineColumn := fs.String("polinecolumn", "PO Line", "The name of the optional PO line column in the file. "+
+		"When a row has no PO line, the PO line of the item in Alma is used.")
+	department := fs.String("department", "", "The code of the receiving department. Required. Use the conf-dump subcommand to see the possible values.")
+	library := fs.String("library", "", "The code of the library the receiving department is in. Required.")
+	receiveDate := fs.String("receivedate", "", "The date the items were received, like 2006-01-02. Defaults to today.")
+	scanIn := fs.Bool("scanin", false, "Scan the items in at a circ desk in the library after they are received.")
+	circdesk := fs.String("circdesk", api.DefaultCircDesk, "The circ desk code used with the scanin flag.")
+	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
+	fs.Usage = func() {
+		description := "Receive items on purchase order lines at a receiving department, from a set of items,\n" +
+			"an Analytics report, or a vendor invoice CSV of barcodes. The items can then be scanned in at a circ desk."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	config := &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.BibsRead, api.AcqWrite},
+		FlagSet:      fs,
+		CodeChecks: []subcommand.CodeCheck{
+			{Flag: "library", Value: library, Source: subcommand.LibraryCodes},
+			{Flag: "department", Value: department, Source: subcommand.DepartmentCodes},
+		},
+	}
+	config.ValidateFlags = func() error {
+		if *file == "" {
+			err := source.Validate()
+			if err != nil {
+				return err
+			}
+		} else if !source.Empty() {
+			return fmt.Errorf("the file flag can't be used with a set or Analytics report")
+		}
+		if *department == "" || *library == "" {
+			return fmt.Errorf("a department code and library code are required")
+		}
+		if *receiveDate != "" {
+			_, err := time.Parse("2006-01-02", *receiveDate)
+			if err != nil {
+				return fmt.Errorf("the receive date must be formatted like 2006-01-02")
+			}
+		}
+		if *scanIn {
+			config.CodeChecks = append(config.CodeChecks,
+				subcommand.CodeCheck{Flag: "circdesk", Value: circdesk, Source: subcommand.CircDeskCodes(library)})
+		}
+		return nil
+	}
+	config.ExtraCapabilities = func() (capabilities []api.Capability) {
+		// Scanning in the received items changes them.
+		if *scanIn {
+			capabilities = append(capabilities, api.BibsWrite)
+		}
+		return append(capabilities, source.Capabilities()...)
+	}
+	config.Estimate = func(ctx context.Context, c *api.Client) (calls int, err error) {
+		// Each item takes a call to get it, a call to receive it, and a call to scan it in.
+		perItem := 2
+		if *scanIn {
+			perItem++
+		}
+		if *file != "" {
+			receipts, err := readFile(*file, *barcodeColumn, *poLineColumn)
+			return len(receipts) * perItem, err
+		}
+		return source.Estimate(perItem)(ctx, c)
+	}
+	config.Run = func(ctx context.Context, c *api.Client) error {
+		if *dryrun {
+			log.Println("Running in dry run mode, no changes will be made in Alma.")
+		} else {
+			log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+		}
+		items, errs := []api.Item{}, []error{}
+		if *file != "" {
+			receipts, err := readFile(*file, *barcodeColumn, *poLineColumn)
+			if err != nil {
+				return err
+			}
+			items, errs = ReceiptItems(ctx, c, receipts)
+		} else {
+			members, err := source.Members(ctx, c)
+			if err != nil {
+				return err
+			}
+			items, errs = c.ItemMembersItems(ctx, members)
+		}
+		received := []api.Item{}
+		receiveErrs := []error{}
+		scannedIn := []api.Item{}
+		scanInErrs := []error{}
+		if !*dryrun {
+			options := api.ReceiveOptions{Department: *department, Library: *library, ReceiveDate: *receiveDate}
+			received, receiveErrs = c.POLineItemsReceive(ctx, items, options)
+			if *scanIn {
+				members := []api.Member{}
+				for _, item := range received {
+					members = append(members, api.Member{ID: item.PID, Link: item.Link})
+				}
+				scannedIn, scanInErrs = c.ItemMembersScanIn(ctx, members, api.ScanInOptions{CircDesk: *circdesk, Library: *library})
+			}
+		}
+		receivedMap := map[string]api.Item{}
+		for _, item := range received {
+			receivedMap[item.PID] = item
+		}
+		scannedInMap := map[string]api.Item{}
+		for _, item := range scannedIn {
+			scannedInMap[item.PID] = item
+		}
+		w := csv.NewWriter(os.Stdout)
+		err := w.Write([]string{"Barcode", "PO Line", "Item PID", "Title", "Process Type", "Received in Alma", "Scanned in in Alma"})
+		if err != nil {
+			return fmt.Errorf("error writing csv header: %w", err)
+		}
+		for _, item := range items {
+			processType := item.ProcessType.Text
+			inReceived, inScannedIn := "no", "no"
+			if receivedItem, found := receivedMap[item.PID]; found {
+				processType = receivedItem.ProcessType.Text
+				inReceived = "yes"
+			}
+			if scannedInItem, found := scannedInMap[item.PID]; found {
+				processType = scannedInItem.ProcessType.Text
+				inScannedIn = "yes"
+			}
+			err := w.Write([]string{item.Barcode, item.POLine, item.PID, item.Title, processType, inReceived, inScannedIn})
+			if err != nil {
+				return fmt.Errorf("error writing line to csv: %w", err)
+			}
+		}
+		w.Flush()
+		err = w.Error()
+		if err != nil {
+			return fmt.Errorf("error after flushing csv: %w", err)
+		}
+		log.Printf("%v item(s) received, %v item(s) scanned in.\n", len(received), len(scannedIn))
+		errs = append(append(errs, receiveErrs...), scanInErrs...)
+		if len(errs) != 0 {
+			for _, err := range errs {
+				log.Println(err)
+			}
+			return fmt.Errorf("%v error(s) occured when receiving items", len(errs))
+		}
+		return nil
+	}
+	return config
+}
+
+// readFile reads the receipts from the vendor invoice CSV at the path.
+func readFile(path, barcodeColumn, poLineColumn string) (receipts []Receipt, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return receipts, err
+	}
+	defer f.Close()
+	return ReadReceipts(f, barcodeColumn, poLineColumn)
+}
+
+// ReceiptItems returns the items with the barcodes of the receipts.
+// The PO line of an item is replaced by the PO line of its receipt, if the receipt has one.
+func ReceiptItems(ctx context.Context, c *api.Client, receipts []Receipt) (items []api.Item, errs []error) {
+	barcodes := []string{}
+	poLines := map[string]string{}
+	for _, receipt := range receipts {
+		barcodes = append(barcodes, receipt.Barcode)
+		poLines[receipt.Barcode] = receipt.POLine
+	}
+	items, errs = c.ItemsFromBarcodes(ctx, barcodes)
+	for i, item := range items {
+		if poLines[item.Barcode] != "" {
+			items[i].POLine = poLines[item.Barcode]
+		}
+	}
+	return items, errs
+}
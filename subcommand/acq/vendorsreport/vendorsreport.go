@@ -0,0 +1,99 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package vendorsreport provides a subcommand which reports vendor contact and account data.
+package vendorsreport
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("acq-vendors-report", flag.ExitOnError)
+	status := fs.String("status", "ACTIVE", "The vendor status to report on, ACTIVE, INACTIVE, or ALL.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	fs.Usage = func() {
+		description := "Report the contact information and accounts of vendors, one line per vendor account.\n" +
+			"Vendors without accounts have one line with empty account columns."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.AcqRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			return report.ValidateFormat(*format)
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			vendors, errs := c.Vendors(ctx, *status)
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving vendors", len(errs))
+			}
+			sort.Slice(vendors, func(i, j int) bool {
+				return vendors[i].Code < vendors[j].Code
+			})
+			w, err := report.NewWriter(os.Stdout, *format, []string{"Code", "Name", "Status", "Roles", "Currencies", "Email", "Phone", "Address",
+				"Account Code", "Account Description", "Account Status", "Payment Methods"})
+			if err != nil {
+				return err
+			}
+			for _, vendor := range vendors {
+				line := []string{vendor.Code, vendor.Name, vendor.Status.Value, Roles(vendor), values(vendor.Currency),
+					vendor.ContactInfo.PreferredEmail(), vendor.ContactInfo.PreferredPhone(), vendor.ContactInfo.PreferredAddress()}
+				accounts := vendor.Accounts
+				if len(accounts) == 0 {
+					accounts = []api.VendorAccount{{}}
+				}
+				for _, account := range accounts {
+					err := w.Write(append(line, account.Code, account.Description, account.Status.Value, values(account.PaymentMethod)))
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return w.Close()
+		},
+	}
+}
+
+// Roles returns the roles of the vendor, like "Material supplier; Licensor".
+func Roles(vendor api.Vendor) string {
+	roles := []string{}
+	if vendor.MaterialSupplier {
+		roles = append(roles, "Material supplier")
+	}
+	if vendor.AccessProvider {
+		roles = append(roles, "Access provider")
+	}
+	if vendor.Licensor {
+		roles = append(roles, "Licensor")
+	}
+	if vendor.Governmental {
+		roles = append(roles, "Governmental")
+	}
+	return strings.Join(roles, "; ")
+}
+
+// values joins the codes of the values.
+func values(vs []api.Value) string {
+	codes := []string{}
+	for _, v := range vs {
+		codes = append(codes, v.Value)
+	}
+	return strings.Join(codes, "; ")
+}
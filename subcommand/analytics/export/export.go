@@ -0,0 +1,132 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package export provides a subcommand which exports Analytics reports.
+package export
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("analytics-export", flag.ExitOnError)
+	path := fs.String("path", "", "The path of the report in the Analytics catalog, like '/shared/Carleton University/Reports/Items'. Required.")
+	filter := fs.String("filter", "", "An optional filter on the report, as a sawx XML expression.")
+	filterFile := fs.String("filterfile", "", "The path to a file holding the filter, instead of the filter flag.")
+	limit := fs.Int("limit", api.AnalyticsMaxLimit, fmt.Sprintf("The number of rows in each page of results, a multiple of %v from %v to %v.",
+		api.AnalyticsMinLimit, api.AnalyticsMinLimit, api.AnalyticsMaxLimit))
+	allColumns := fs.Bool("allcolumns", false, "Include the placeholder column Analytics adds to the start of every report.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage+" Numbers in JSON reports are not quoted.")
+	fs.Usage = func() {
+		description := "Export an Analytics report, with the column headings of the report.\n" +
+			"Rows are written as each page of results is returned, so large reports can be exported."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.AnalyticsRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			if *path == "" {
+				return fmt.Errorf("a report path is required")
+			}
+			if *limit < api.AnalyticsMinLimit || *limit > api.AnalyticsMaxLimit || *limit%api.AnalyticsMinLimit != 0 {
+				return fmt.Errorf("the limit must be a multiple of %v from %v to %v", api.AnalyticsMinLimit, api.AnalyticsMinLimit, api.AnalyticsMaxLimit)
+			}
+			if *filterFile != "" {
+				if *filter != "" {
+					return fmt.Errorf("the filter and filterfile flags can't be used together")
+				}
+				contents, err := ioutil.ReadFile(*filterFile)
+				if err != nil {
+					return fmt.Errorf("reading the filter file failed: %w", err)
+				}
+				*filter = string(contents)
+			}
+			return report.ValidateFormat(*format)
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			var w report.Writer
+			var columns []api.AnalyticsColumn
+			rows := 0
+			err := c.AnalyticsReportPages(ctx, api.AnalyticsQuery{Path: *path, Filter: *filter, Limit: *limit},
+				func(pageColumns []api.AnalyticsColumn, pageRows []api.AnalyticsRow) (err error) {
+					if w == nil {
+						columns = Columns(pageColumns, *allColumns)
+						header := []string{}
+						for _, column := range columns {
+							header = append(header, column.Heading)
+						}
+						w, err = report.NewWriter(os.Stdout, *format, header)
+						if err != nil {
+							return err
+						}
+					}
+					for _, row := range pageRows {
+						values, err := Values(row, columns)
+						if err != nil {
+							return err
+						}
+						err = w.WriteValues(values)
+						if err != nil {
+							return err
+						}
+					}
+					rows += len(pageRows)
+					log.Printf("%v row(s) exported.\n", rows)
+					return nil
+				})
+			if w != nil {
+				closeErr := w.Close()
+				if err == nil {
+					err = closeErr
+				}
+			}
+			return err
+		},
+	}
+}
+
+// Columns returns the columns of the report, without the placeholder column Analytics adds unless all is true.
+func Columns(columns []api.AnalyticsColumn, all bool) (exported []api.AnalyticsColumn) {
+	for _, column := range columns {
+		if !all && column.Placeholder() {
+			continue
+		}
+		exported = append(exported, column)
+	}
+	return exported
+}
+
+// Values returns the typed values of the row's columns, with dates formatted like 2006-01-02
+// and times formatted like 2006-01-02T15:04:05.
+func Values(row api.AnalyticsRow, columns []api.AnalyticsColumn) (values []interface{}, err error) {
+	values, err = row.Values(columns)
+	if err != nil {
+		return values, err
+	}
+	for i, value := range values {
+		t, ok := value.(time.Time)
+		if !ok {
+			continue
+		}
+		if columns[i].Kind() == api.KindDate {
+			values[i] = t.Format("2006-01-02")
+		} else {
+			values[i] = t.Format("2006-01-02T15:04:05")
+		}
+	}
+	return values, nil
+}
@@ -23,11 +23,11 @@ import (
 // Config returns a new subcommand config.
 func Config(envPrefix string) *subcommand.Config {
 	fs := flag.NewFlagSet("bibs-clean-up-call-numbers", flag.ExitOnError)
-	ID := fs.String("setid", "", "The ID of the set we are processing. This flag or setname are required.")
-	name := fs.String("setname", "", "The name of the set we are processing. This flag or setid are required.")
+	source := subcommand.NewMemberSource(fs, subcommand.BibContent)
 	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
 	fs.Usage = func() {
-		description := "Clean up the call numbers in the holdings records for a set of bib records.\n" +
+		description := "Clean up the call numbers in the holdings records for a set of bib records,\n" +
+			"or the bibs in an Analytics report.\n" +
 			"\n" +
 			"The following rules are run on the call numbers:\n" +
 			"Add a space between a number then a letter.\n" +
@@ -38,45 +38,37 @@ func Config(envPrefix string) *subcommand.Config {
 		subcommand.Usage(fs, envPrefix, description)
 	}
 	return &subcommand.Config{
-		ReadAccess:  []string{"/almaws/v1/conf"},
-		WriteAccess: []string{"/almaws/v1/bibs"},
-		FlagSet:     fs,
+		Capabilities: []api.Capability{api.ConfRead, api.BibsWrite},
+		// Each bib takes a call for its holdings list, and a GET and PUT for each holding, assuming one holding per bib.
+		Estimate: source.Estimate(3),
+		FlagSet:  fs,
 		ValidateFlags: func() error {
-			return subcommand.ValidateSetNameAndSetIDFlags(*name, *ID)
+			return source.Validate()
 		},
+		ExtraCapabilities: source.Capabilities,
 		Run: func(ctx context.Context, c *api.Client) error {
 			if *dryrun {
 				log.Println("Running in dry run mode, no changes will be made in Alma.")
 			} else {
 				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
 			}
-			set, err := c.SetFromNameOrID(ctx, *name, *ID)
+			members, err := source.Members(ctx, c)
 			if err != nil {
 				return err
 			}
-			if set.Type != "ITEMIZED" || set.Content != "BIB_MMS" {
-				return fmt.Errorf("the set must be an itemized set of bibs")
-			}
-			members, errs := c.SetMembers(ctx, set)
-			if len(errs) != 0 {
-				for _, err := range errs {
-					log.Println(err)
-				}
-				return fmt.Errorf("%v error(s) occured when retrieving the members of '%v' (ID %v)", len(errs), set.Name, set.ID)
-			}
 			holdingListMembers, errs := c.BibMembersHoldingListMembers(ctx, members)
 			if len(errs) != 0 {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when retrieving the holding list members of '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when retrieving the holding list members of %v", len(errs), source)
 			}
 			holdings, errs := c.HoldingListMembersToHoldings(ctx, holdingListMembers)
 			if len(errs) != 0 {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when retrieving the holdings records of '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when retrieving the holdings records of %v", len(errs), source)
 			}
 			// Store the original call number for later reporting.
 			originalCallNumberMap := map[string]string{}
@@ -135,7 +127,7 @@ func Config(envPrefix string) *subcommand.Config {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when updating the call numbers of holdings records of bibs in '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when updating the call numbers of holdings records of bibs in %v", len(errs), source)
 			}
 			return nil
 		},
@@ -21,23 +21,26 @@ import (
 // Config returns a new subcommand config.
 func Config(envPrefix string) *subcommand.Config {
 	fs := flag.NewFlagSet("items-cancel-requests", flag.ExitOnError)
-	ID := fs.String("setid", "", "The ID of the set we are processing. This flag or setname are required.")
-	name := fs.String("setname", "", "The name of the set we are processing. This flag or setid are required.")
+	source := subcommand.NewMemberSource(fs, subcommand.ItemContent)
 	rType := fs.String("type", "", "The request type to cancel. ex: WORK_ORDER")
 	subType := fs.String("subtype", "", "The request subtype to cancel.")
 	reason := fs.String("reason", "", "Code of the cancel reason. Must be a value from the code table 'RequestCancellationReasons'.")
 	note := fs.String("note", "", "Note with additional information regarding the cancellation")
 	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
 	fs.Usage = func() {
-		description := "Cancel item requests of type and/or subtype on items in the given set."
+		description := "Cancel item requests of type and/or subtype on items in the given set or Analytics report."
 		subcommand.Usage(fs, envPrefix, description)
 	}
 	return &subcommand.Config{
-		ReadAccess:  []string{"/almaws/v1/conf"},
-		WriteAccess: []string{"/almaws/v1/bibs"},
-		FlagSet:     fs,
+		Capabilities: []api.Capability{api.ConfRead, api.BibsWrite},
+		// Each item takes a call for its requests, and a DELETE for each request, assuming one request per item.
+		Estimate: source.Estimate(2),
+		FlagSet:  fs,
+		CodeChecks: []subcommand.CodeCheck{
+			{Flag: "reason", Value: reason, Source: subcommand.CodeTableCodes("RequestCancellationReasons")},
+		},
 		ValidateFlags: func() error {
-			err := subcommand.ValidateSetNameAndSetIDFlags(*name, *ID)
+			err := source.Validate()
 			if err != nil {
 				return err
 			}
@@ -45,36 +48,27 @@ func Config(envPrefix string) *subcommand.Config {
 				return fmt.Errorf("a request type or a request sub type are required")
 			}
 			if *reason == "" {
-				return fmt.Errorf("a reason is required, try the 'dump-conf' subcommand to find a value from the 'RequestCancellationReasons' table")
+				return fmt.Errorf("a reason is required, try the 'conf-dump' subcommand to find a value from the 'RequestCancellationReasons' table")
 			}
 			return nil
 		},
+		ExtraCapabilities: source.Capabilities,
 		Run: func(ctx context.Context, c *api.Client) error {
 			if *dryrun {
 				log.Println("Running in dry run mode, no changes will be made in Alma.")
 			} else {
 				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
 			}
-			set, err := c.SetFromNameOrID(ctx, *name, *ID)
+			members, err := source.Members(ctx, c)
 			if err != nil {
 				return err
 			}
-			if set.Type != "ITEMIZED" || set.Content != "ITEM" {
-				return fmt.Errorf("the set must be an itemized set of items")
-			}
-			members, errs := c.SetMembers(ctx, set)
-			if len(errs) != 0 {
-				for _, err := range errs {
-					log.Println(err)
-				}
-				return fmt.Errorf("%v error(s) occured when retrieving the members of '%v' (ID %v)", len(errs), set.Name, set.ID)
-			}
 			requests, errs := c.ItemMembersUserRequests(ctx, members)
 			if len(errs) != 0 {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when retrieving requests on members of '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when retrieving requests on members of %v", len(errs), source)
 			}
 			matching := []api.UserRequest{}
 			for _, request := range requests {
@@ -129,7 +123,7 @@ func Config(envPrefix string) *subcommand.Config {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when cancelling requests on members of '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when cancelling requests on members of %v", len(errs), source)
 			}
 			return nil
 		},
@@ -0,0 +1,118 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package createrequest provides a subcommand which creates work order and move requests on items in a set.
+package createrequest
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("items-create-request", flag.ExitOnError)
+	source := subcommand.NewMemberSource(fs, subcommand.ItemContent)
+	rType := fs.String("type", "WORK_ORDER", "The request type to create. WORK_ORDER or MOVE.")
+	subType := fs.String("subtype", "", "The request subtype. For WORK_ORDER requests, this is the work order type code. ex: Binding")
+	department := fs.String("department", "", "The code of the department the items are sent to. Use the conf-dump subcommand to see the possible values.")
+	status := fs.String("status", "", "The status of the new requests.")
+	note := fs.String("note", "", "Note with additional information regarding the request.")
+	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
+	fs.Usage = func() {
+		description := "Create a work order or move request on each item in the given set or Analytics report."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.BibsWrite},
+		Estimate:     source.Estimate(1),
+		FlagSet:      fs,
+		CodeChecks: []subcommand.CodeCheck{
+			{Flag: "department", Value: department, Source: subcommand.DepartmentCodes},
+		},
+		ValidateFlags: func() error {
+			err := source.Validate()
+			if err != nil {
+				return err
+			}
+			if *rType != "WORK_ORDER" && *rType != "MOVE" {
+				return fmt.Errorf("the request type must be WORK_ORDER or MOVE")
+			}
+			if *rType == "WORK_ORDER" && *subType == "" {
+				return fmt.Errorf("a work order type is required in the subtype flag when creating WORK_ORDER requests")
+			}
+			if *department == "" {
+				return fmt.Errorf("a department code is required")
+			}
+			return nil
+		},
+		ExtraCapabilities: source.Capabilities,
+		Run: func(ctx context.Context, c *api.Client) error {
+			if *dryrun {
+				log.Println("Running in dry run mode, no changes will be made in Alma.")
+			} else {
+				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+			}
+			members, err := source.Members(ctx, c)
+			if err != nil {
+				return err
+			}
+			request := api.UserRequest{
+				Type:              *rType,
+				SubType:           *subType,
+				Status:            *status,
+				TargetDestination: *department,
+				Comment:           *note,
+			}
+			created := []api.UserRequest{}
+			errs := []error{}
+			if !*dryrun {
+				created, errs = c.ItemMembersCreateRequest(ctx, members, request)
+			}
+			createdMap := map[string]api.UserRequest{}
+			for _, request := range created {
+				createdMap[request.Member.Link] = request
+			}
+			w := csv.NewWriter(os.Stdout)
+			err = w.Write([]string{"Item Link", "Request Link", "Request Type", "Request Subtype", "Created in Alma"})
+			if err != nil {
+				return fmt.Errorf("error writing csv header: %w", err)
+			}
+			for _, member := range members {
+				line := []string{member.Link}
+				request, inCreated := createdMap[member.Link]
+				if inCreated {
+					line = append(line, request.Link, request.Type, request.SubType, "yes")
+				} else {
+					line = append(line, "", *rType, *subType, "no")
+				}
+				err := w.Write(line)
+				if err != nil {
+					return fmt.Errorf("error writing line to csv: %w", err)
+				}
+			}
+			w.Flush()
+			err = w.Error()
+			if err != nil {
+				return fmt.Errorf("error after flushing csv: %w", err)
+			}
+			log.Printf("%v request(s) created.\n", len(created))
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when creating requests on members of %v", len(errs), source)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package locationreport provides a subcommand which counts the items in a set per location.
+package locationreport
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+)
+
+// libraryLocation is a location code qualified by its library code.
+type libraryLocation struct {
+	Library  string
+	Location string
+}
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("location-report", flag.ExitOnError)
+	source := subcommand.NewMemberSource(fs, subcommand.ItemContent)
+	fs.Usage = func() {
+		description := "Count the items in the given set or Analytics report per library and location."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.BibsRead},
+		Estimate:     source.Estimate(1),
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			return source.Validate()
+		},
+		ExtraCapabilities: source.Capabilities,
+		Run: func(ctx context.Context, c *api.Client) error {
+			members, err := source.Members(ctx, c)
+			if err != nil {
+				return err
+			}
+			items, errs := c.ItemMembersItems(ctx, members)
+			counts := map[libraryLocation]int{}
+			for _, item := range items {
+				counts[libraryLocation{item.Library.Text, item.Location.Text}]++
+			}
+			// Retrieve the configuration of the locations in each library with items in the set.
+			// Items without a library are reported with blank location details.
+			libraryLocations := map[string]api.Locations{}
+			for key := range counts {
+				_, seen := libraryLocations[key.Library]
+				if !seen && key.Library != "" {
+					locations, err := c.Locations(ctx, key.Library)
+					if err != nil {
+						return err
+					}
+					libraryLocations[key.Library] = locations
+				}
+			}
+			keys := make([]libraryLocation, 0, len(counts))
+			for key := range counts {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				if keys[i].Library != keys[j].Library {
+					return keys[i].Library < keys[j].Library
+				}
+				return keys[i].Location < keys[j].Location
+			})
+			w := csv.NewWriter(os.Stdout)
+			err = w.Write([]string{"Library", "Location", "Location Name", "Location Type", "Fulfillment Unit", "Call Number Type", "Item Count"})
+			if err != nil {
+				return fmt.Errorf("error writing csv header: %w", err)
+			}
+			for _, key := range keys {
+				line := []string{key.Library, key.Location}
+				location, found := libraryLocations[key.Library].Location(key.Location)
+				if found {
+					line = append(line, location.Name, location.Type.Text, location.FulfillmentUnit.Text, location.CallNumberType.Text)
+				} else {
+					line = append(line, "", "", "", "")
+				}
+				line = append(line, strconv.Itoa(counts[key]))
+				err := w.Write(line)
+				if err != nil {
+					return fmt.Errorf("error writing line to csv: %w", err)
+				}
+			}
+			w.Flush()
+			err = w.Error()
+			if err != nil {
+				return fmt.Errorf("error after flushing csv: %w", err)
+			}
+			log.Printf("%v item(s) found in %v location(s).\n", len(items), len(keys))
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving the items in %v", len(errs), source)
+			}
+			return nil
+		},
+	}
+}
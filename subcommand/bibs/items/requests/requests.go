@@ -21,33 +21,24 @@ import (
 // Config returns a new subcommand config.
 func Config(envPrefix string) *subcommand.Config {
 	fs := flag.NewFlagSet("items-requests", flag.ExitOnError)
-	ID := fs.String("setid", "", "The ID of the set we are processing. This flag or setname are required.")
-	name := fs.String("setname", "", "The name of the set we are processing. This flag or setid are required.")
+	source := subcommand.NewMemberSource(fs, subcommand.ItemContent)
 	fs.Usage = func() {
-		description := "View requests on items in the given set."
+		description := "View requests on items in the given set or Analytics report."
 		subcommand.Usage(fs, envPrefix, description)
 	}
 	return &subcommand.Config{
-		ReadAccess: []string{"/almaws/v1/conf", "/almaws/v1/bibs"},
-		FlagSet:    fs,
+		Capabilities: []api.Capability{api.ConfRead, api.BibsRead},
+		Estimate:     source.Estimate(1),
+		FlagSet:      fs,
 		ValidateFlags: func() error {
-			return subcommand.ValidateSetNameAndSetIDFlags(*name, *ID)
+			return source.Validate()
 		},
+		ExtraCapabilities: source.Capabilities,
 		Run: func(ctx context.Context, c *api.Client) error {
-			set, err := c.SetFromNameOrID(ctx, *name, *ID)
+			members, err := source.Members(ctx, c)
 			if err != nil {
 				return err
 			}
-			if set.Type != "ITEMIZED" || set.Content != "ITEM" {
-				return fmt.Errorf("the set must be an itemized set of items")
-			}
-			members, errs := c.SetMembers(ctx, set)
-			if len(errs) != 0 {
-				for _, err := range errs {
-					log.Println(err)
-				}
-				return fmt.Errorf("%v error(s) occured when retrieving the members of '%v' (ID %v)", len(errs), set.Name, set.ID)
-			}
 			requests, errs := c.ItemMembersUserRequests(ctx, members)
 			typeSubTypeCount := map[string]int{}
 			for _, request := range requests {
@@ -78,7 +69,7 @@ func Config(envPrefix string) *subcommand.Config {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when retrieving requests on members of '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when retrieving requests on members of %v", len(errs), source)
 			}
 			return nil
 		},
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/cu-library/almatoolkit/api"
 	"github.com/cu-library/almatoolkit/subcommand"
@@ -21,64 +22,87 @@ import (
 // Config returns a new subcommand config.
 func Config(envPrefix string) *subcommand.Config {
 	fs := flag.NewFlagSet("items-scan-in", flag.ExitOnError)
-	ID := fs.String("setid", "", "The ID of the set we are processing. This flag or setname are required.")
-	name := fs.String("setname", "", "The name of the set we are processing. This flag or setid are required.")
-	circdesk := fs.String("circdesk", api.DefaultCircDesk, "The circ desk code. The possible values are not available through the API, "+
-		"see https://developers.exlibrisgroup.com/alma/apis/docs/xsd/rest_item_loan.xsd/?tags=GET.")
-	library := fs.String("library", "", "The library code. Use the conf-dump subcommand to see the possible values.")
+	source := subcommand.NewMemberSource(fs, subcommand.ItemContent)
+	circdesk := fs.String("circdesk", "", "The circ desk code. Defaults to the library's only or primary circ desk, or "+api.DefaultCircDesk+". "+
+		"Use the conf-dump subcommand to see the possible values.")
+	library := fs.String("library", "", "The library code. Required. Use the conf-dump subcommand to see the possible values.")
+	department := fs.String("department", "", "The work order department code. When set, items are scanned in at this department instead of the circ desk. "+
+		"Use the conf-dump subcommand to see the possible values.")
+	workOrderType := fs.String("workordertype", "", "The work order type code. Used with the department flag.")
+	status := fs.String("status", "", "The work order status code the items should move to. Used with the department flag.")
+	done := fs.Bool("done", false, "Mark the current work order step as done. Used with the department flag.")
+	confirm := fs.Bool("confirm", false, "Confirm scan in operations which Alma would otherwise refuse to perform without confirmation.")
 	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
 	fs.Usage = func() {
-		description := "Scan the members of a set of items in."
+		description := "Scan the members of a set of items, or the items in an Analytics report, in at a circ desk\n" +
+			"or a work order department.\n" +
+			"When a department is provided, items can be moved through work order steps using the\n" +
+			"workordertype, status, and done flags."
 		subcommand.Usage(fs, envPrefix, description)
 	}
 	return &subcommand.Config{
-		ReadAccess:  []string{"/almaws/v1/conf"},
-		WriteAccess: []string{"/almaws/v1/bibs"},
-		FlagSet:     fs,
+		Capabilities: []api.Capability{api.ConfRead, api.BibsWrite},
+		Estimate:     source.Estimate(1),
+		FlagSet:      fs,
+		CodeChecks: []subcommand.CodeCheck{
+			{Flag: "library", Value: library, Source: subcommand.LibraryCodes},
+			{Flag: "circdesk", Value: circdesk, Source: subcommand.CircDeskCodes(library)},
+			{Flag: "department", Value: department, Source: subcommand.DepartmentCodes},
+		},
 		ValidateFlags: func() error {
-			err := subcommand.ValidateSetNameAndSetIDFlags(*name, *ID)
+			err := source.Validate()
 			if err != nil {
 				return err
 			}
-			if *circdesk == "" {
-				return fmt.Errorf("a circ desk code is required")
-			}
+			// Alma needs the library for scan ins at a department, as well as at a circ desk.
 			if *library == "" {
 				return fmt.Errorf("a library code is required")
 			}
+			if *department == "" && (*workOrderType != "" || *status != "" || *done) {
+				return fmt.Errorf("the workordertype, status, and done flags require a department code")
+			}
 			return nil
 		},
+		ExtraCapabilities: source.Capabilities,
 		Run: func(ctx context.Context, c *api.Client) error {
 			if *dryrun {
 				log.Println("Running in dry run mode, no changes will be made in Alma.")
 			} else {
 				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
 			}
-			set, err := c.SetFromNameOrID(ctx, *name, *ID)
+			if *department == "" && *circdesk == "" {
+				desk, err := defaultCircDesk(ctx, c, *library)
+				if err != nil {
+					return err
+				}
+				*circdesk = desk
+				log.Printf("Using circ desk %v in library %v.\n", *circdesk, *library)
+			}
+			members, err := source.Members(ctx, c)
 			if err != nil {
 				return err
 			}
-			if set.Type != "ITEMIZED" || set.Content != "ITEM" {
-				return fmt.Errorf("the set must be an itemized set of items")
-			}
-			members, errs := c.SetMembers(ctx, set)
-			if len(errs) != 0 {
-				for _, err := range errs {
-					log.Println(err)
-				}
-				return fmt.Errorf("%v error(s) occured when retrieving the members of '%v' (ID %v)", len(errs), set.Name, set.ID)
-			}
 			items := []api.Item{}
-			errs = []error{}
+			errs := []error{}
 			if !*dryrun {
-				items, errs = c.ItemMembersScanIn(ctx, members, *circdesk, *library)
+				options := api.ScanInOptions{
+					CircDesk:      *circdesk,
+					Library:       *library,
+					Department:    *department,
+					WorkOrderType: *workOrderType,
+					Status:        *status,
+					Done:          *done,
+					Confirm:       *confirm,
+				}
+				items, errs = c.ItemMembersScanIn(ctx, members, options)
 			}
 			scannedInMap := map[string]api.Item{}
 			for _, item := range items {
 				scannedInMap[item.Link] = item
 			}
 			w := csv.NewWriter(os.Stdout)
-			err = w.Write([]string{"Item Link", "Title", "Author", "Call Number", "Barcode", "Scanned in in Alma"})
+			err = w.Write([]string{"Item Link", "MMS ID", "Title", "Author", "Call Number", "Barcode",
+				"Library", "Location", "Process Type", "Work Order Type", "Work Order At", "Scanned in in Alma"})
 			if err != nil {
 				return fmt.Errorf("error writing csv header: %w", err)
 			}
@@ -86,9 +110,10 @@ func Config(envPrefix string) *subcommand.Config {
 				line := []string{member.Link}
 				item, inScannedIn := scannedInMap[member.Link]
 				if inScannedIn {
-					line = append(line, item.MMSID, item.Title, item.Author, item.CallNumber, item.Barcode, "yes")
+					line = append(line, item.MMSID, item.Title, item.Author, item.CallNumber, item.Barcode,
+						item.Library.Text, item.Location.Text, item.ProcessType.Text, item.WorkOrderType.Text, item.WorkOrderAt.Text, "yes")
 				} else {
-					line = append(line, "", "", "", "", "", "no")
+					line = append(line, "", "", "", "", "", "", "", "", "", "", "no")
 				}
 				err := w.Write(line)
 				if err != nil {
@@ -100,14 +125,55 @@ func Config(envPrefix string) *subcommand.Config {
 			if err != nil {
 				return fmt.Errorf("error after flushing csv: %w", err)
 			}
+			processTypeCount := map[string]int{}
+			for _, item := range items {
+				processType := fmt.Sprintf("Process Type: %v Library: %v Location: %v", item.ProcessType.Text, item.Library.Text, item.Location.Text)
+				processTypeCount[processType] = processTypeCount[processType] + 1
+			}
+			for processType, count := range processTypeCount {
+				log.Println(processType, "Count:", count)
+			}
 			log.Printf("%v successful scan in operation(s).\n", len(items))
 			if len(errs) != 0 {
 				for _, err := range errs {
 					log.Println(err)
 				}
-				return fmt.Errorf("%v error(s) occured when scanning in members of '%v' (ID %v)", len(errs), set.Name, set.ID)
+				return fmt.Errorf("%v error(s) occured when scanning in members of %v", len(errs), source)
 			}
 			return nil
 		},
 	}
 }
+
+// defaultCircDesk returns the library's only circ desk, its primary circ desk, or the default circ desk if the library has it.
+func defaultCircDesk(ctx context.Context, c *api.Client, library string) (code string, err error) {
+	desks, err := c.CircDesks(ctx, library)
+	if err != nil {
+		return code, err
+	}
+	return ChooseCircDesk(desks, library)
+}
+
+// ChooseCircDesk returns the only circ desk, the primary circ desk, or the default circ desk, in that order.
+func ChooseCircDesk(desks api.CircDesks, library string) (code string, err error) {
+	if len(desks.CircDesks) == 0 {
+		return code, fmt.Errorf("library %v has no circ desks", library)
+	}
+	if len(desks.CircDesks) == 1 {
+		return desks.CircDesks[0].Code, nil
+	}
+	desk, found := desks.Primary()
+	if found {
+		return desk.Code, nil
+	}
+	_, found = desks.CircDesk(api.DefaultCircDesk)
+	if found {
+		return api.DefaultCircDesk, nil
+	}
+	codes := []string{}
+	for _, desk := range desks.CircDesks {
+		codes = append(codes, desk.Code)
+	}
+	return code, fmt.Errorf("library %v has more than one circ desk and none are primary, choose one of %v with the circdesk flag",
+		library, strings.Join(codes, ", "))
+}
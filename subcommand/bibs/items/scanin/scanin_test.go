@@ -0,0 +1,41 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package scanin
+
+import (
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestChooseCircDesk checks that the only desk is chosen first, then the primary desk, then the default desk.
+func TestChooseCircDesk(t *testing.T) {
+	tests := []struct {
+		desks []api.CircDesk
+		want  string
+	}{
+		{[]api.CircDesk{{Code: "FRONT"}}, "FRONT"},
+		{[]api.CircDesk{{Code: api.DefaultCircDesk}, {Code: "FRONT", Primary: "true"}}, "FRONT"},
+		{[]api.CircDesk{{Code: "FRONT", Primary: "false"}, {Code: api.DefaultCircDesk}}, api.DefaultCircDesk},
+	}
+	for _, test := range tests {
+		got, err := ChooseCircDesk(api.CircDesks{CircDesks: test.desks}, "MAIN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("ChooseCircDesk(%+v) = %v, want %v", test.desks, got, test.want)
+		}
+	}
+	_, err := ChooseCircDesk(api.CircDesks{}, "MAIN")
+	if err == nil || err.Error() != "library MAIN has no circ desks" {
+		t.Fatalf("unexpected error for a library without desks: %v", err)
+	}
+	_, err = ChooseCircDesk(api.CircDesks{CircDesks: []api.CircDesk{{Code: "A"}, {Code: "B"}}}, "MAIN")
+	if err == nil {
+		t.Fatal("expected an error for a library with several desks and none primary")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package subcommand
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// MaxSuggestions is the maximum number of codes suggested when a flag's code is not valid.
+const MaxSuggestions = 5
+
+// CodeSource returns the codes which are valid for a flag, as configured in Alma.
+type CodeSource func(context.Context, *api.Client) ([]string, error)
+
+// CodeCheck declares that the value of a flag must be a code which is configured in Alma.
+type CodeCheck struct {
+	Flag   string     // The name of the flag being checked.
+	Value  *string    // The value of the flag. Empty values are not checked.
+	Source CodeSource // The source of valid codes for the flag.
+}
+
+// LibraryCodes returns the codes of the libraries configured for the Institution.
+func LibraryCodes(ctx context.Context, c *api.Client) (codes []string, err error) {
+	libraries, err := c.Libraries(ctx)
+	if err != nil {
+		return codes, err
+	}
+	for _, library := range libraries.Libraries {
+		codes = append(codes, library.Code)
+	}
+	return codes, nil
+}
+
+// DepartmentCodes returns the codes of the departments configured for the Institution.
+func DepartmentCodes(ctx context.Context, c *api.Client) (codes []string, err error) {
+	departments, err := c.Departments(ctx)
+	if err != nil {
+		return codes, err
+	}
+	for _, department := range departments.Departments {
+		codes = append(codes, department.Code)
+	}
+	return codes, nil
+}
+
+// CircDeskCodes returns a source for the codes of the circulation desks in the library.
+// The library is read when the source is called, after flags are parsed.
+func CircDeskCodes(library *string) CodeSource {
+	return func(ctx context.Context, c *api.Client) (codes []string, err error) {
+		if *library == "" {
+			return codes, fmt.Errorf("a library code is required to look up its circ desks")
+		}
+		desks, err := c.CircDesks(ctx, *library)
+		if err != nil {
+			return codes, err
+		}
+		for _, desk := range desks.CircDesks {
+			codes = append(codes, desk.Code)
+		}
+		return codes, nil
+	}
+}
+
+// CodeTableCodes returns a source for the codes in the named code table.
+func CodeTableCodes(name string) CodeSource {
+	return func(ctx context.Context, c *api.Client) (codes []string, err error) {
+		table, err := c.CodeTable(ctx, name)
+		if err != nil {
+			return codes, err
+		}
+		for _, row := range table.Rows {
+			codes = append(codes, row.Code)
+		}
+		return codes, nil
+	}
+}
+
+// ValidateCodes ensures the value of each checked flag is a code from its source.
+// The error for an unknown code includes the closest valid codes as suggestions.
+func ValidateCodes(ctx context.Context, c *api.Client, checks []CodeCheck) error {
+	for _, check := range checks {
+		if *check.Value == "" {
+			continue
+		}
+		codes, err := check.Source(ctx, c)
+		if err != nil {
+			return fmt.Errorf("retrieving the valid codes for the %v flag failed: %w", check.Flag, err)
+		}
+		if contains(codes, *check.Value) {
+			continue
+		}
+		suggestions := Suggest(*check.Value, codes, MaxSuggestions)
+		if len(suggestions) == 0 {
+			return fmt.Errorf("'%v' is not a valid code for the %v flag, try the 'conf-dump' subcommand to find a value", *check.Value, check.Flag)
+		}
+		return fmt.Errorf("'%v' is not a valid code for the %v flag, did you mean: %v", *check.Value, check.Flag, strings.Join(suggestions, ", "))
+	}
+	return nil
+}
+
+// Suggest returns at most max codes which are similar to value, the most similar first.
+func Suggest(value string, codes []string, max int) (suggestions []string) {
+	type scored struct {
+		code     string
+		distance int
+	}
+	lowerValue := strings.ToLower(value)
+	candidates := []scored{}
+	for _, code := range codes {
+		lowerCode := strings.ToLower(code)
+		distance := levenshtein(lowerValue, lowerCode)
+		// Allow roughly one edit for every three characters, or a match on a part of the code.
+		if distance <= 1+len(lowerValue)/3 || strings.Contains(lowerCode, lowerValue) || strings.Contains(lowerValue, lowerCode) {
+			candidates = append(candidates, scored{code, distance})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].code < candidates[j].code
+	})
+	for i := 0; i < len(candidates) && i < max; i++ {
+		suggestions = append(suggestions, candidates[i].code)
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func contains(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
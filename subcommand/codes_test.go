@@ -0,0 +1,59 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package subcommand
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSuggest checks that similar codes are suggested, closest first.
+func TestSuggest(t *testing.T) {
+	codes := []string{"MAIN", "MAINLIB", "LAW", "MUSIC", "ARCHIVES"}
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"MIAN", []string{"MAIN"}},
+		{"main", []string{"MAIN", "MAINLIB"}},
+		{"LAWS", []string{"LAW"}},
+		{"ARCHIVE", []string{"ARCHIVES"}},
+		{"XYZZY", nil},
+	}
+	for _, test := range tests {
+		got := Suggest(test.value, codes, MaxSuggestions)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Suggest(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+// TestSuggestMax checks that no more than max codes are suggested.
+func TestSuggestMax(t *testing.T) {
+	codes := []string{"A1", "A2", "A3", "A4"}
+	got := Suggest("A", codes, 2)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 suggestions, got %q", got)
+	}
+}
+
+// TestLevenshtein checks the edit distance between strings.
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"MAIN", "MIAN", 2},
+	}
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
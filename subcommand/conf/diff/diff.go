@@ -0,0 +1,94 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package diff provides a subcommand which compares snapshots of Alma configuration.
+package diff
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/conf/dump"
+	"github.com/cu-library/almatoolkit/subcommand/conf/snapshot"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("conf-diff", flag.ExitOnError)
+	from := fs.String("from", "", "The path to the snapshot to compare from, made with 'conf-dump -format json' or 'conf-dump -format yaml'. Required.")
+	to := fs.String("to", "", "The path to the snapshot to compare to. If not set, the live configuration is used.")
+	tables := fs.String("tables", "", "A comma separated list of the code tables to include when using the live configuration. "+
+		"Defaults to the code tables in the from snapshot.")
+	fs.Usage = func() {
+		description := "Compare two snapshots of the Alma configuration, or a snapshot and the live configuration,\n" +
+			"and report the codes which were added, removed, or changed."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			if *from == "" {
+				return fmt.Errorf("a snapshot to compare from is required")
+			}
+			return nil
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			before, err := snapshot.Load(*from)
+			if err != nil {
+				return err
+			}
+			var after snapshot.Snapshot
+			if *to != "" {
+				after, err = snapshot.Load(*to)
+				if err != nil {
+					return err
+				}
+			} else {
+				names := []string{}
+				for _, table := range before.CodeTables {
+					names = append(names, table.Name)
+				}
+				if *tables != "" {
+					names = dump.TableNames(ctx, c, *tables)
+				}
+				var errs []error
+				after, errs = snapshot.Take(ctx, c, names)
+				if len(errs) != 0 {
+					for _, err := range errs {
+						log.Println(err)
+					}
+					return fmt.Errorf("%v error(s) occured when retrieving the live configuration", len(errs))
+				}
+			}
+			log.Printf("Comparing %v (%v) to %v (%v).\n", before.Host, before.Taken, after.Host, after.Taken)
+			changes := snapshot.Diff(before, after)
+			w := csv.NewWriter(os.Stdout)
+			err = w.Write([]string{"Section", "Code Table", "Code", "Change", "Property", "Before", "After"})
+			if err != nil {
+				return fmt.Errorf("error writing csv header: %w", err)
+			}
+			for _, change := range changes {
+				err := w.Write([]string{change.Section, change.Table, change.Code, change.Kind, change.Property, change.Before, change.After})
+				if err != nil {
+					return fmt.Errorf("error writing line to csv: %w", err)
+				}
+			}
+			w.Flush()
+			err = w.Error()
+			if err != nil {
+				return fmt.Errorf("error after flushing csv: %w", err)
+			}
+			log.Printf("%v change(s) found.\n", len(changes))
+			return nil
+		},
+	}
+}
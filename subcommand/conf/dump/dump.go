@@ -11,76 +11,40 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/cu-library/almatoolkit/api"
 	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/conf/snapshot"
 )
 
 // Config returns a new subcommand config.
 func Config(envPrefix string) *subcommand.Config {
 	fs := flag.NewFlagSet("conf-dump", flag.ExitOnError)
+	format := fs.String("format", snapshot.FormatText, "The output format. text, json, or yaml. "+
+		"The json and yaml output can be compared using the conf-diff subcommand.")
+	tables := fs.String("tables", "", "A comma separated list of the code tables to include. Defaults to all the code tables listed by the API.")
 	fs.Usage = func() {
-		description := "Print the output of the library and departments endpoints, and the known code tables.\n" +
-			"The list of known code tables comes from:\n" +
+		description := "Print the output of the library, circulation desk, location, and departments endpoints, and the code tables.\n" +
+			"The list of code tables comes from the API. If it is not available, the list from\n" +
 			"https://developers.exlibrisgroup.com/blog/almas-code-tables-api-list-of-code-tables/\n" +
+			"is used instead. Code tables which are not found are skipped with a warning.\n" +
 			"This command is meant to help run other subcommands which sometimes need a particular\n" +
 			"code from a code table or the code for a library or department."
-		subcommand.UsageNoFlags(fs, description)
+		subcommand.Usage(fs, envPrefix, description)
 	}
 	return &subcommand.Config{
-		ReadAccess: []string{"/almaws/v1/conf"},
-		FlagSet:    fs,
+		Capabilities: []api.Capability{api.ConfRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			return snapshot.ValidateFormat(*format)
+		},
 		Run: func(ctx context.Context, c *api.Client) error {
-			libraries, err := c.Libraries(ctx)
-			if err != nil {
-				return err
-			}
-			fmt.Println("Libraries:")
-			for _, library := range libraries.Libraries {
-				fmt.Printf("%v (%v)\n", library.Code, library.Name)
-				fmt.Printf("Description: %v\n", library.Description)
-				fmt.Printf("Resource Sharing: %v\n", library.ResourceSharing)
-				fmt.Printf("Campus: %v (%v)\n", library.Campus.Text, library.Campus.Desc)
-				fmt.Printf("Proxy: %v\n", library.Proxy)
-				fmt.Printf("Default Location: %v (%v)\n", library.DefaultLocation.Text, library.DefaultLocation.Desc)
-				fmt.Println()
-			}
-			departments, err := c.Departments(ctx)
+			s, errs := snapshot.Take(ctx, c, TableNames(ctx, c, *tables))
+			err := s.Write(os.Stdout, *format)
 			if err != nil {
-				return err
-			}
-			fmt.Println("Departments:")
-			for _, department := range departments.Departments {
-				fmt.Printf("%v (%v)\n", department.Code, department.Name)
-				fmt.Printf("Type: %v (%v)\n", department.Type.Text, department.Type.Desc)
-				fmt.Printf("Work Days: %v\n", department.WorkDays)
-				fmt.Printf("Printer: %v (%v)\n", department.Printer.Text, department.Printer.Desc)
-				fmt.Printf("Owner: %v (%v)\n", department.Owner.Text, department.Owner.Desc)
-				fmt.Println("Served Libraries:")
-				for _, library := range department.ServedLibraries.Library {
-					fmt.Printf("  %v (%v)\n", library.Text, library.Desc)
-				}
-				fmt.Println("Operators:")
-				for _, operator := range department.Operators.Operator {
-					fmt.Printf("  %v (%v)\n", operator.PrimaryID, operator.FullName)
-				}
-				fmt.Println()
-			}
-			tables, errs := c.CodeTables(ctx)
-			fmt.Println("Code Tables:")
-			for _, table := range tables {
-				fmt.Printf("%v (%v)\n", table.Name, table.Description)
-				fmt.Printf("Subsystem: %v (%v)\n", table.SubSystem.Text, table.SubSystem.Desc)
-				fmt.Printf("Patron Facing: %v\n", table.PatronFacing)
-				fmt.Printf("Language: %v (%v)\n", table.Language.Text, table.Language.Desc)
-				fmt.Println("Scope:")
-				fmt.Printf("  Institution : %v (%v)\n", table.Scope.InstitutionID.Text, table.Scope.InstitutionID.Desc)
-				fmt.Printf("  Library : %v (%v)\n", table.Scope.LibraryID.Text, table.Scope.LibraryID.Desc)
-				fmt.Println("Rows:")
-				for _, row := range table.Rows {
-					fmt.Printf("%v (%v) Default: %v Enabled: %v\n", row.Code, row.Description, row.Default, row.Enabled)
-				}
-				fmt.Println()
+				return fmt.Errorf("error writing configuration: %w", err)
 			}
 			if len(errs) != 0 {
 				for _, err := range errs {
@@ -92,3 +56,24 @@ func Config(envPrefix string) *subcommand.Config {
 		},
 	}
 }
+
+// TableNames splits a comma separated list of code table names.
+// An empty list returns the code tables listed by the API,
+// or the known code tables if the API can't list them.
+func TableNames(ctx context.Context, c *api.Client, list string) (names []string) {
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) != 0 {
+		return names
+	}
+	names, err := c.CodeTableNames(ctx)
+	if err != nil {
+		log.Printf("WARNING: Listing code tables failed, using the known code tables instead: %v\n", err)
+		return api.KnownCodeTables
+	}
+	return names
+}
@@ -0,0 +1,454 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package snapshot provides a machine-readable record of Alma configuration which can be compared between runs.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// The formats a snapshot can be written in.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Entry stores a configured code, its name, and its other properties.
+type Entry struct {
+	Code       string            `json:"code" yaml:"code"`
+	Name       string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Properties map[string]string `json:"properties,omitempty" yaml:"properties,omitempty"`
+}
+
+// Table stores a code table and its rows.
+type Table struct {
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties  map[string]string `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Rows        []Entry           `json:"rows" yaml:"rows"`
+}
+
+// Snapshot stores the configuration of an Alma institution at a point in time.
+type Snapshot struct {
+	Host        string    `json:"host" yaml:"host"`
+	Taken       time.Time `json:"taken" yaml:"taken"`
+	Libraries   []Entry   `json:"libraries" yaml:"libraries"`
+	CircDesks   []Entry   `json:"circ_desks" yaml:"circ_desks"`
+	Locations   []Entry   `json:"locations" yaml:"locations"`
+	Departments []Entry   `json:"departments" yaml:"departments"`
+	CodeTables  []Table   `json:"code_tables" yaml:"code_tables"`
+}
+
+// Take returns a snapshot of the configuration of the institution.
+// Only the code tables with the given names are included.
+// Code tables which are not found, like retired tables, are logged as warnings and skipped.
+func Take(ctx context.Context, c *api.Client, tables []string) (snapshot Snapshot, errs []error) {
+	snapshot.Host = c.Host
+	snapshot.Taken = time.Now().UTC()
+	libraries, err := c.Libraries(ctx)
+	if err != nil {
+		return snapshot, append(errs, err)
+	}
+	for _, library := range libraries.Libraries {
+		snapshot.Libraries = append(snapshot.Libraries, Entry{
+			Code: library.Code,
+			Name: library.Name,
+			Properties: map[string]string{
+				"description":      library.Description,
+				"resource_sharing": strconv.FormatBool(library.ResourceSharing),
+				"campus":           valueDesc(library.Campus.Text, library.Campus.Desc),
+				"proxy":            library.Proxy,
+				"default_location": valueDesc(library.DefaultLocation.Text, library.DefaultLocation.Desc),
+			},
+		})
+		desks, err := c.CircDesks(ctx, library.Code)
+		if err != nil {
+			return snapshot, append(errs, err)
+		}
+		for _, summary := range desks.CircDesks {
+			desk, err := c.CircDesk(ctx, library.Code, summary.Code)
+			if err != nil {
+				return snapshot, append(errs, err)
+			}
+			locations := []string{}
+			for _, location := range desk.Locations {
+				locations = append(locations, location.Code)
+			}
+			snapshot.CircDesks = append(snapshot.CircDesks, Entry{
+				Code: library.Code + "/" + desk.Code,
+				Name: desk.Name,
+				Properties: map[string]string{
+					"description":  desk.Description,
+					"primary":      desk.Primary,
+					"reading_room": desk.ReadingRoom,
+					"hold_shelf":   desk.HoldShelf,
+					"locations":    strings.Join(locations, ", "),
+				},
+			})
+		}
+		locations, err := c.Locations(ctx, library.Code)
+		if err != nil {
+			return snapshot, append(errs, err)
+		}
+		for _, location := range locations.Locations {
+			snapshot.Locations = append(snapshot.Locations, Entry{
+				Code: library.Code + "/" + location.Code,
+				Name: location.Name,
+				Properties: map[string]string{
+					"external_name":            location.ExternalName,
+					"type":                     valueDesc(location.Type.Text, location.Type.Desc),
+					"fulfillment_unit":         valueDesc(location.FulfillmentUnit.Text, location.FulfillmentUnit.Desc),
+					"call_number_type":         valueDesc(location.CallNumberType.Text, location.CallNumberType.Desc),
+					"accession_placement":      valueDesc(location.AccessionPlacement.Text, location.AccessionPlacement.Desc),
+					"suppress_from_publishing": strconv.FormatBool(location.SuppressFromPublishing),
+					"remote_storage":           strconv.FormatBool(location.RemoteStorage),
+					"map":                      location.Map,
+				},
+			})
+		}
+	}
+	departments, err := c.Departments(ctx)
+	if err != nil {
+		return snapshot, append(errs, err)
+	}
+	for _, department := range departments.Departments {
+		served := []string{}
+		for _, library := range department.ServedLibraries.Library {
+			served = append(served, library.Text)
+		}
+		operators := []string{}
+		for _, operator := range department.Operators.Operator {
+			operators = append(operators, operator.PrimaryID)
+		}
+		snapshot.Departments = append(snapshot.Departments, Entry{
+			Code: department.Code,
+			Name: department.Name,
+			Properties: map[string]string{
+				"type":             valueDesc(department.Type.Text, department.Type.Desc),
+				"work_days":        department.WorkDays,
+				"printer":          valueDesc(department.Printer.Text, department.Printer.Desc),
+				"owner":            valueDesc(department.Owner.Text, department.Owner.Desc),
+				"served_libraries": strings.Join(served, ", "),
+				"operators":        strings.Join(operators, ", "),
+				"description":      department.Description,
+			},
+		})
+	}
+	codeTables, missing, errs := c.CodeTables(ctx, tables)
+	sort.Strings(missing)
+	for _, name := range missing {
+		log.Printf("WARNING: Code table %v was not found, it may have been retired.\n", name)
+	}
+	for _, table := range codeTables {
+		rows := []Entry{}
+		for _, row := range table.Rows {
+			rows = append(rows, Entry{
+				Code: row.Code,
+				Name: row.Description,
+				Properties: map[string]string{
+					"default": row.Default,
+					"enabled": row.Enabled,
+				},
+			})
+		}
+		snapshot.CodeTables = append(snapshot.CodeTables, Table{
+			Name:        table.Name,
+			Description: table.Description,
+			Properties: map[string]string{
+				"sub_system":    valueDesc(table.SubSystem.Text, table.SubSystem.Desc),
+				"patron_facing": table.PatronFacing,
+				"language":      valueDesc(table.Language.Text, table.Language.Desc),
+				"institution":   valueDesc(table.Scope.InstitutionID.Text, table.Scope.InstitutionID.Desc),
+				"library":       valueDesc(table.Scope.LibraryID.Text, table.Scope.LibraryID.Desc),
+			},
+			Rows: rows,
+		})
+	}
+	// Code tables are retrieved concurrently, sort them so snapshots are stable.
+	sort.Slice(snapshot.CodeTables, func(i, j int) bool {
+		return snapshot.CodeTables[i].Name < snapshot.CodeTables[j].Name
+	})
+	return snapshot, errs
+}
+
+// valueDesc joins a value and its description the same way the text output does.
+func valueDesc(value, desc string) string {
+	if desc == "" {
+		return value
+	}
+	return fmt.Sprintf("%v (%v)", value, desc)
+}
+
+// ValidateFormat ensures the format is one snapshots can be written in.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("the format must be one of %v, %v, or %v", FormatText, FormatJSON, FormatYAML)
+}
+
+// Write writes the snapshot to w in the format.
+func (s Snapshot) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		err := encoder.Encode(s)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatText:
+		return s.writeText(w)
+	}
+	return ValidateFormat(format)
+}
+
+// writeText writes the snapshot as human readable text.
+func (s Snapshot) writeText(w io.Writer) error {
+	sections := []struct {
+		title   string
+		entries []Entry
+	}{
+		{"Libraries", s.Libraries},
+		{"Circulation Desks", s.CircDesks},
+		{"Locations", s.Locations},
+		{"Departments", s.Departments},
+	}
+	for _, section := range sections {
+		_, err := fmt.Fprintf(w, "%v:\n", section.title)
+		if err != nil {
+			return err
+		}
+		for _, entry := range section.entries {
+			_, err := fmt.Fprintf(w, "%v (%v)\n", entry.Code, entry.Name)
+			if err != nil {
+				return err
+			}
+			err = writeProperties(w, entry.Properties, "")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "Code Tables:")
+	if err != nil {
+		return err
+	}
+	for _, table := range s.CodeTables {
+		_, err := fmt.Fprintf(w, "%v (%v)\n", table.Name, table.Description)
+		if err != nil {
+			return err
+		}
+		err = writeProperties(w, table.Properties, "")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, "rows:")
+		if err != nil {
+			return err
+		}
+		for _, row := range table.Rows {
+			_, err := fmt.Fprintf(w, "  %v (%v)\n", row.Code, row.Name)
+			if err != nil {
+				return err
+			}
+			err = writeProperties(w, row.Properties, "    ")
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeProperties writes the properties sorted by key.
+func writeProperties(w io.Writer, properties map[string]string, indent string) error {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, err := fmt.Fprintf(w, "%v%v: %v\n", indent, key, properties[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads a snapshot from a JSON or YAML file. Files ending in .yaml or .yml are read as YAML.
+func Load(path string) (snapshot Snapshot, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &snapshot)
+	default:
+		err = json.Unmarshal(data, &snapshot)
+	}
+	if err != nil {
+		return snapshot, fmt.Errorf("reading snapshot %v failed: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// The kinds of changes between snapshots.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change stores one difference between two snapshots.
+type Change struct {
+	Section  string // The section of the snapshot, like "libraries" or "code_tables".
+	Table    string // The code table name, for changes in code tables.
+	Code     string // The code which was added, removed, or changed.
+	Kind     string // Added, Removed, or Changed.
+	Property string // The property which changed, for Changed.
+	Before   string // The value before the change.
+	After    string // The value after the change.
+}
+
+// Diff returns the changes needed to go from the before snapshot to the after snapshot.
+func Diff(before, after Snapshot) (changes []Change) {
+	changes = append(changes, diffEntries("libraries", "", before.Libraries, after.Libraries)...)
+	changes = append(changes, diffEntries("circ_desks", "", before.CircDesks, after.CircDesks)...)
+	changes = append(changes, diffEntries("locations", "", before.Locations, after.Locations)...)
+	changes = append(changes, diffEntries("departments", "", before.Departments, after.Departments)...)
+	beforeTables := map[string]Table{}
+	for _, table := range before.CodeTables {
+		beforeTables[table.Name] = table
+	}
+	afterTables := map[string]Table{}
+	for _, table := range after.CodeTables {
+		afterTables[table.Name] = table
+	}
+	for _, name := range sortedKeys(beforeTables, afterTables) {
+		beforeTable, inBefore := beforeTables[name]
+		afterTable, inAfter := afterTables[name]
+		switch {
+		case !inAfter:
+			changes = append(changes, Change{Section: "code_tables", Table: name, Kind: Removed, Before: beforeTable.Description})
+		case !inBefore:
+			changes = append(changes, Change{Section: "code_tables", Table: name, Kind: Added, After: afterTable.Description})
+		default:
+			tableBefore := Entry{Name: beforeTable.Description, Properties: beforeTable.Properties}
+			tableAfter := Entry{Name: afterTable.Description, Properties: afterTable.Properties}
+			changes = append(changes, diffEntry("code_tables", name, tableBefore, tableAfter)...)
+			changes = append(changes, diffEntries("code_tables", name, beforeTable.Rows, afterTable.Rows)...)
+		}
+	}
+	return changes
+}
+
+// diffEntries returns the changes between two lists of entries, matched by code.
+func diffEntries(section, table string, before, after []Entry) (changes []Change) {
+	beforeMap := map[string]Entry{}
+	for _, entry := range before {
+		beforeMap[entry.Code] = entry
+	}
+	afterMap := map[string]Entry{}
+	for _, entry := range after {
+		afterMap[entry.Code] = entry
+	}
+	codes := []string{}
+	for code := range beforeMap {
+		codes = append(codes, code)
+	}
+	for code := range afterMap {
+		_, inBefore := beforeMap[code]
+		if !inBefore {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		beforeEntry, inBefore := beforeMap[code]
+		afterEntry, inAfter := afterMap[code]
+		switch {
+		case !inAfter:
+			changes = append(changes, Change{Section: section, Table: table, Code: code, Kind: Removed, Before: beforeEntry.Name})
+		case !inBefore:
+			changes = append(changes, Change{Section: section, Table: table, Code: code, Kind: Added, After: afterEntry.Name})
+		default:
+			changes = append(changes, diffEntry(section, table, beforeEntry, afterEntry)...)
+		}
+	}
+	return changes
+}
+
+// diffEntry returns the changes to the name and properties of an entry.
+func diffEntry(section, table string, before, after Entry) (changes []Change) {
+	if before.Name != after.Name {
+		changes = append(changes, Change{section, table, before.Code, Changed, "name", before.Name, after.Name})
+	}
+	keys := map[string]bool{}
+	for key := range before.Properties {
+		keys[key] = true
+	}
+	for key := range after.Properties {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		if before.Properties[key] != after.Properties[key] {
+			changes = append(changes, Change{section, table, before.Code, Changed, key, before.Properties[key], after.Properties[key]})
+		}
+	}
+	return changes
+}
+
+// sortedKeys returns the sorted union of the keys of the table maps.
+func sortedKeys(a, b map[string]Table) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		_, inA := a[key]
+		if !inA {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSnapshot() Snapshot {
+	return Snapshot{
+		Host:  "api-ca.hosted.exlibrisgroup.com",
+		Taken: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+		Libraries: []Entry{
+			{Code: "MAIN", Name: "Main Library", Properties: map[string]string{"campus": "MAIN (Main Campus)"}},
+			{Code: "LAW", Name: "Law Library"},
+		},
+		CodeTables: []Table{
+			{
+				Name:        "RequestCancellationReasons",
+				Description: "Request cancellation reasons",
+				Rows: []Entry{
+					{Code: "CannotBeFulfilled", Name: "Cannot be fulfilled", Properties: map[string]string{"enabled": "true"}},
+					{Code: "RequestSwitched", Name: "Request switched", Properties: map[string]string{"enabled": "true"}},
+				},
+			},
+		},
+	}
+}
+
+// TestDiffNoChanges checks that identical snapshots have no changes.
+func TestDiffNoChanges(t *testing.T) {
+	changes := Diff(testSnapshot(), testSnapshot())
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+// TestDiff checks that added, removed, and changed codes are reported.
+func TestDiff(t *testing.T) {
+	before := testSnapshot()
+	after := testSnapshot()
+	after.Libraries = []Entry{
+		{Code: "MAIN", Name: "Main Library", Properties: map[string]string{"campus": "NORTH (North Campus)"}},
+		{Code: "MUSIC", Name: "Music Library"},
+	}
+	after.CodeTables[0].Rows = []Entry{
+		{Code: "CannotBeFulfilled", Name: "Cannot be fulfilled", Properties: map[string]string{"enabled": "false"}},
+		{Code: "RequestSwitched", Name: "Request was switched", Properties: map[string]string{"enabled": "true"}},
+	}
+	after.CodeTables = append(after.CodeTables, Table{Name: "UserGroups"})
+	want := []Change{
+		{Section: "libraries", Code: "LAW", Kind: Removed, Before: "Law Library"},
+		{Section: "libraries", Code: "MAIN", Kind: Changed, Property: "campus", Before: "MAIN (Main Campus)", After: "NORTH (North Campus)"},
+		{Section: "libraries", Code: "MUSIC", Kind: Added, After: "Music Library"},
+		{Section: "code_tables", Table: "RequestCancellationReasons", Code: "CannotBeFulfilled", Kind: Changed, Property: "enabled", Before: "true", After: "false"},
+		{Section: "code_tables", Table: "RequestCancellationReasons", Code: "RequestSwitched", Kind: Changed, Property: "name", Before: "Request switched", After: "Request was switched"},
+		{Section: "code_tables", Table: "UserGroups", Kind: Added},
+	}
+	got := Diff(before, after)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected changes\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+// TestWriteLoad checks that JSON and YAML snapshots can be read back.
+func TestWriteLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range []struct {
+		format string
+		file   string
+	}{
+		{FormatJSON, "snapshot.json"},
+		{FormatYAML, "snapshot.yaml"},
+	} {
+		var b bytes.Buffer
+		err := testSnapshot().Write(&b, test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, test.file)
+		err = ioutil.WriteFile(path, b.Bytes(), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes := Diff(testSnapshot(), loaded)
+		if len(changes) != 0 {
+			t.Fatalf("%v snapshot changed when written and loaded: %v", test.format, changes)
+		}
+		if !loaded.Taken.Equal(testSnapshot().Taken) {
+			t.Fatalf("%v snapshot time changed when written and loaded", test.format)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package tableapply
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// The kinds of tables rows can be applied to.
+const (
+	KindCode    = "code"
+	KindMapping = "mapping"
+)
+
+// Row stores the fields of a table row by field name.
+// Code table rows have the fields code, description, default, and enabled.
+// Mapping table rows have the fields column0, column1, column2, and enabled, and some tables have more columns, like column3.
+// Fields of live rows which aren't listed here are kept by the name of their element.
+type Row map[string]string
+
+// fields returns the field names for the kind of table, the default key field first.
+func fields(kind string) []string {
+	if kind == KindMapping {
+		return []string{"column0", "column1", "column2", "enabled"}
+	}
+	return []string{"code", "description", "default", "enabled"}
+}
+
+// mappingColumn matches the names of the columns of mapping tables.
+var mappingColumn = regexp.MustCompile(`^column[0-9]+$`)
+
+// ValidField returns true if rows of the kind of table can have the field.
+func ValidField(kind, field string) bool {
+	for _, f := range fields(kind) {
+		if f == field {
+			return true
+		}
+	}
+	return kind == KindMapping && mappingColumn.MatchString(field)
+}
+
+// DefaultKeys returns the fields which identify a row in the kind of table, if no others are chosen.
+func DefaultKeys(kind string) []string {
+	return fields(kind)[:1]
+}
+
+// rowFromOther returns a row with the known fields and the other fields of a table row.
+func rowFromOther(row Row, other []api.XMLText) Row {
+	for _, field := range other {
+		row[field.XMLName.Local] = field.Text
+	}
+	return row
+}
+
+// setOther returns the other fields of a table row updated from the row.
+// The other fields of the live row are kept in order, and new fields are added after them in order of name.
+func setOther(row Row, live []api.XMLText, known []string) (other []api.XMLText) {
+	used := map[string]bool{}
+	for _, field := range known {
+		used[field] = true
+	}
+	for _, field := range live {
+		used[field.XMLName.Local] = true
+		if value, present := row[field.XMLName.Local]; present {
+			field.Text = value
+		}
+		other = append(other, field)
+	}
+	added := []string{}
+	for field := range row {
+		if !used[field] {
+			added = append(added, field)
+		}
+	}
+	sort.Strings(added)
+	for _, field := range added {
+		other = append(other, api.XMLText{XMLName: xml.Name{Local: field}, Text: row[field]})
+	}
+	return other
+}
+
+// CodeTableRows returns the rows of a code table.
+func CodeTableRows(table api.CodeTable) (rows []Row) {
+	for _, row := range table.Rows {
+		rows = append(rows, rowFromOther(Row{"code": row.Code, "description": row.Description, "default": row.Default, "enabled": row.Enabled}, row.Other))
+	}
+	return rows
+}
+
+// SetCodeTableRows replaces the rows of a code table.
+// The rows must be in the same order as the table's rows, with new rows at the end.
+func SetCodeTableRows(table *api.CodeTable, rows []Row) {
+	live := table.Rows
+	table.Rows = []api.CodeTableRow{}
+	for i, row := range rows {
+		var other []api.XMLText
+		if i < len(live) {
+			other = live[i].Other
+		}
+		table.Rows = append(table.Rows, api.CodeTableRow{
+			Code:        row["code"],
+			Description: row["description"],
+			Default:     row["default"],
+			Enabled:     row["enabled"],
+			Other:       setOther(row, other, fields(KindCode)),
+		})
+	}
+}
+
+// MappingTableRows returns the rows of a mapping table.
+func MappingTableRows(table api.MappingTable) (rows []Row) {
+	for _, row := range table.Rows {
+		rows = append(rows, rowFromOther(Row{"column0": row.Column0, "column1": row.Column1, "column2": row.Column2, "enabled": row.Enabled}, row.Other))
+	}
+	return rows
+}
+
+// SetMappingTableRows replaces the rows of a mapping table.
+// The rows must be in the same order as the table's rows, with new rows at the end.
+func SetMappingTableRows(table *api.MappingTable, rows []Row) {
+	live := table.Rows
+	table.Rows = []api.MappingTableRow{}
+	for i, row := range rows {
+		var other []api.XMLText
+		if i < len(live) {
+			other = live[i].Other
+		}
+		table.Rows = append(table.Rows, api.MappingTableRow{
+			Column0: row["column0"],
+			Column1: row["column1"],
+			Column2: row["column2"],
+			Enabled: row["enabled"],
+			Other:   setOther(row, other, fields(KindMapping)),
+		})
+	}
+}
+
+// ReadRows reads rows from a CSV file with a header line, or a JSON file holding an array of objects.
+// Files ending in .json are read as JSON, where true, false, and numbers are read as text.
+// Field names must be valid for the kind of table, and every row needs a value for each key field.
+func ReadRows(path, kind string, keys []string) (rows []Row, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return rows, err
+	}
+	defer f.Close()
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		rows, err = readJSONRows(f)
+		if err != nil {
+			return rows, fmt.Errorf("reading rows from %v failed: %w", path, err)
+		}
+	} else {
+		rows, err = readCSVRows(f)
+		if err != nil {
+			return rows, fmt.Errorf("reading rows from %v failed: %w", path, err)
+		}
+	}
+	for i, row := range rows {
+		for field := range row {
+			if !ValidField(kind, field) {
+				return rows, fmt.Errorf("row %v has the field '%v', which %v tables don't have, try one of %v", i+1, field, kind, strings.Join(fields(kind), ", "))
+			}
+		}
+		for _, key := range keys {
+			if row[key] == "" {
+				return rows, fmt.Errorf("row %v is missing a value for '%v'", i+1, key)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// readJSONRows reads rows from a JSON array of objects. Values which are true, false, or numbers are converted to text.
+func readJSONRows(r io.Reader) (rows []Row, err error) {
+	objects := []map[string]interface{}{}
+	d := json.NewDecoder(r)
+	// Numbers are kept as they were written, so 1.0 isn't changed to 1.
+	d.UseNumber()
+	err = d.Decode(&objects)
+	if err != nil {
+		return rows, err
+	}
+	for i, object := range objects {
+		row := Row{}
+		for field, value := range object {
+			switch v := value.(type) {
+			case string:
+				row[field] = v
+			case bool:
+				row[field] = strconv.FormatBool(v)
+			case json.Number:
+				row[field] = v.String()
+			case nil:
+				row[field] = ""
+			default:
+				return rows, fmt.Errorf("the value of '%v' in row %v must be text, true, false, or a number", field, i+1)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readCSVRows reads rows from CSV data, using the first line as field names.
+func readCSVRows(r io.Reader) (rows []Row, err error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return rows, err
+	}
+	if len(records) == 0 {
+		return rows, fmt.Errorf("no header line found")
+	}
+	header := records[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	for _, record := range records[1:] {
+		row := Row{}
+		for i, value := range record {
+			row[header[i]] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// The kinds of differences between live rows and applied rows.
+const (
+	Added   = "added"
+	Changed = "changed"
+)
+
+// Difference stores a change made to a table by applying rows.
+type Difference struct {
+	Key    string // The values of the key fields of the row, separated by slashes.
+	Kind   string // Added or Changed.
+	Field  string // The field which changed, for Changed.
+	Before string // The value before the change.
+	After  string // The value after the change.
+}
+
+// rowKey returns the values of the key fields of the row, separated by slashes.
+func rowKey(row Row, keys []string) string {
+	values := []string{}
+	for _, key := range keys {
+		values = append(values, row[key])
+	}
+	return strings.Join(values, "/")
+}
+
+// Apply returns the live rows updated with the applied rows, and the differences between them.
+// Rows are matched on the values of all the key fields. Applied rows which don't match a live row are added.
+// Only the fields present in an applied row are changed, and live rows which aren't matched are left as they are.
+// An error is returned if two live rows or two applied rows have the same key, since they can't be matched.
+func Apply(live, applied []Row, keys []string) (updated []Row, differences []Difference, err error) {
+	isKey := map[string]bool{}
+	for _, key := range keys {
+		isKey[key] = true
+	}
+	index := map[string]int{}
+	for _, row := range live {
+		copied := Row{}
+		for field, value := range row {
+			copied[field] = value
+		}
+		key := rowKey(row, keys)
+		if _, found := index[key]; found {
+			return updated, differences, fmt.Errorf("more than one live row has the key %v, choose key fields which identify each row", key)
+		}
+		index[key] = len(updated)
+		updated = append(updated, copied)
+	}
+	seen := map[string]bool{}
+	for _, row := range applied {
+		key := rowKey(row, keys)
+		if seen[key] {
+			return updated, differences, fmt.Errorf("more than one applied row has the key %v", key)
+		}
+		seen[key] = true
+		i, found := index[key]
+		if !found {
+			index[key] = len(updated)
+			updated = append(updated, row)
+			differences = append(differences, Difference{Key: key, Kind: Added})
+			continue
+		}
+		changed := []string{}
+		for field := range row {
+			if !isKey[field] {
+				changed = append(changed, field)
+			}
+		}
+		sort.Strings(changed)
+		for _, field := range changed {
+			value := row[field]
+			if value != updated[i][field] {
+				differences = append(differences, Difference{key, Changed, field, updated[i][field], value})
+				updated[i][field] = value
+			}
+		}
+	}
+	return updated, differences, nil
+}
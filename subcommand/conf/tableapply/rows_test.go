@@ -0,0 +1,172 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package tableapply
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestApply checks that applied rows change and add live rows.
+func TestApply(t *testing.T) {
+	live := []Row{
+		{"code": "A", "description": "Alpha", "default": "false", "enabled": "true"},
+		{"code": "B", "description": "Beta", "default": "false", "enabled": "true"},
+	}
+	applied := []Row{
+		{"code": "B", "enabled": "false"},
+		{"code": "C", "description": "Gamma", "enabled": "true"},
+	}
+	updated, differences, err := Apply(live, applied, DefaultKeys(KindCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUpdated := []Row{
+		{"code": "A", "description": "Alpha", "default": "false", "enabled": "true"},
+		{"code": "B", "description": "Beta", "default": "false", "enabled": "false"},
+		{"code": "C", "description": "Gamma", "enabled": "true"},
+	}
+	if !reflect.DeepEqual(updated, wantUpdated) {
+		t.Fatalf("unexpected updated rows\ngot:  %v\nwant: %v", updated, wantUpdated)
+	}
+	wantDifferences := []Difference{
+		{Key: "B", Kind: Changed, Field: "enabled", Before: "true", After: "false"},
+		{Key: "C", Kind: Added},
+	}
+	if !reflect.DeepEqual(differences, wantDifferences) {
+		t.Fatalf("unexpected differences\ngot:  %v\nwant: %v", differences, wantDifferences)
+	}
+	if live[1]["enabled"] != "true" {
+		t.Fatal("Apply changed the live rows")
+	}
+}
+
+// TestApplyKeys checks that rows are matched on all the key fields, and that duplicate keys are rejected.
+func TestApplyKeys(t *testing.T) {
+	live := []Row{
+		{"column0": "A", "column1": "1", "column2": "x", "column3": "kept", "enabled": "true"},
+		{"column0": "A", "column1": "2", "column2": "y", "column3": "kept", "enabled": "true"},
+	}
+	applied := []Row{{"column0": "A", "column1": "2", "column2": "z"}}
+	_, _, err := Apply(live, applied, DefaultKeys(KindMapping))
+	if err == nil {
+		t.Fatal("expected an error when live rows have the same key")
+	}
+	updated, differences, err := Apply(live, applied, []string{"column0", "column1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUpdated := []Row{
+		{"column0": "A", "column1": "1", "column2": "x", "column3": "kept", "enabled": "true"},
+		{"column0": "A", "column1": "2", "column2": "z", "column3": "kept", "enabled": "true"},
+	}
+	if !reflect.DeepEqual(updated, wantUpdated) {
+		t.Fatalf("unexpected updated rows\ngot:  %v\nwant: %v", updated, wantUpdated)
+	}
+	wantDifferences := []Difference{{Key: "A/2", Kind: Changed, Field: "column2", Before: "y", After: "z"}}
+	if !reflect.DeepEqual(differences, wantDifferences) {
+		t.Fatalf("unexpected differences\ngot:  %v\nwant: %v", differences, wantDifferences)
+	}
+	_, _, err = Apply(live, append(applied, applied[0]), []string{"column0", "column1"})
+	if err == nil {
+		t.Fatal("expected an error when applied rows have the same key")
+	}
+}
+
+// TestSetMappingTableRows checks that the fields of live rows which aren't modelled are kept.
+func TestSetMappingTableRows(t *testing.T) {
+	table := api.MappingTable{Rows: []api.MappingTableRow{{
+		Column0: "A",
+		Column1: "B",
+		Enabled: "true",
+		Other: []api.XMLText{
+			{XMLName: xml.Name{Local: "column4"}, Text: "D"},
+			{XMLName: xml.Name{Local: "column3"}, Text: "C"},
+		},
+	}}}
+	rows := MappingTableRows(table)
+	if rows[0]["column3"] != "C" || rows[0]["column4"] != "D" {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	rows[0]["column3"] = "E"
+	rows = append(rows, Row{"column0": "F", "column5": "G"})
+	SetMappingTableRows(&table, rows)
+	want := []api.MappingTableRow{
+		{Column0: "A", Column1: "B", Enabled: "true", Other: []api.XMLText{
+			{XMLName: xml.Name{Local: "column4"}, Text: "D"},
+			{XMLName: xml.Name{Local: "column3"}, Text: "E"},
+		}},
+		{Column0: "F", Other: []api.XMLText{
+			{XMLName: xml.Name{Local: "column5"}, Text: "G"},
+		}},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Fatalf("unexpected table rows\ngot:  %+v\nwant: %+v", table.Rows, want)
+	}
+}
+
+// TestReadRows checks that CSV and JSON files are read, and unknown fields are rejected.
+func TestReadRows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tableapply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"rows.csv":    "Code,Enabled\nA,false\n",
+		"rows.json":   `[{"code": "A", "enabled": "false"}]`,
+		"typed.json":  `[{"code": "A", "enabled": false}]`,
+		"number.json": `[{"column0": 1.0, "column1": null, "column3": "C"}]`,
+		"nested.json": `[{"code": "A", "enabled": [false]}]`,
+		"bad.csv":     "code,colour\nA,red\n",
+		"nokey.json":  `[{"enabled": "false"}]`,
+		"mapping.csv": "column0,column1\nA,B\n",
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []Row{{"code": "A", "enabled": "false"}}
+	for _, name := range []string{"rows.csv", "rows.json", "typed.json"} {
+		rows, err := ReadRows(filepath.Join(dir, name), KindCode, DefaultKeys(KindCode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Fatalf("unexpected rows from %v: %v", name, rows)
+		}
+	}
+	for _, name := range []string{"bad.csv", "nokey.json", "mapping.csv", "nested.json"} {
+		_, err := ReadRows(filepath.Join(dir, name), KindCode, DefaultKeys(KindCode))
+		if err == nil {
+			t.Fatalf("expected an error reading %v", name)
+		}
+	}
+	_, err = ReadRows(filepath.Join(dir, "mapping.csv"), KindMapping, DefaultKeys(KindMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadRows(filepath.Join(dir, "mapping.csv"), KindMapping, []string{"column0", "column2"})
+	if err == nil {
+		t.Fatal("expected an error reading rows without a value for a key field")
+	}
+	rows, err := ReadRows(filepath.Join(dir, "number.json"), KindMapping, DefaultKeys(KindMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNumber := []Row{{"column0": "1.0", "column1": "", "column3": "C"}}
+	if !reflect.DeepEqual(rows, wantNumber) {
+		t.Fatalf("unexpected rows from number.json: %v", rows)
+	}
+}
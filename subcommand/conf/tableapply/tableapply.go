@@ -0,0 +1,148 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package tableapply provides a subcommand which applies rows from a file to a code table or mapping table.
+package tableapply
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("conf-table-apply", flag.ExitOnError)
+	table := fs.String("table", "", "The name of the code table or mapping table to change. Required.")
+	kind := fs.String("kind", KindCode, "The kind of table. code or mapping.")
+	file := fs.String("file", "", "The path to a CSV or JSON file of rows to apply. Required.")
+	key := fs.String("key", "", "The fields which identify a row, separated by commas, like column0,column1.\n"+
+		"Defaults to code in code tables, and column0 in mapping tables.")
+	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
+	fs.Usage = func() {
+		description := "Apply rows from a CSV or JSON file to a code table or mapping table.\n" +
+			"\n" +
+			"Code table rows have the fields code, description, default, and enabled.\n" +
+			"Mapping table rows have the fields column0, column1, column2, and enabled.\n" +
+			"Some mapping tables have more columns, like column3, and they can be applied too.\n" +
+			"CSV files must start with a header line naming the fields. JSON files must hold an array of objects.\n" +
+			"Rows are matched with the live table by code, or by column0 in mapping tables, unless other key\n" +
+			"fields are chosen. Only the fields present in the file are changed, and rows which don't match a\n" +
+			"live row are added. The fields of live rows which aren't in the file are kept."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	keys := []string{}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.ConfWrite},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			if *table == "" {
+				return fmt.Errorf("a table name is required")
+			}
+			if *kind != KindCode && *kind != KindMapping {
+				return fmt.Errorf("the kind of table must be %v or %v", KindCode, KindMapping)
+			}
+			if *file == "" {
+				return fmt.Errorf("a file of rows is required")
+			}
+			keys = DefaultKeys(*kind)
+			if *key != "" {
+				keys = []string{}
+				for _, field := range strings.Split(*key, ",") {
+					field = strings.ToLower(strings.TrimSpace(field))
+					if !ValidField(*kind, field) {
+						return fmt.Errorf("the key field '%v' isn't a field of %v tables", field, *kind)
+					}
+					keys = append(keys, field)
+				}
+			}
+			return nil
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			if *dryrun {
+				log.Println("Running in dry run mode, no changes will be made in Alma.")
+			} else {
+				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+			}
+			applied, err := ReadRows(*file, *kind, keys)
+			if err != nil {
+				return err
+			}
+			var differences []Difference
+			// update sends the changed table to Alma, it's set below depending on the kind of table.
+			var update func() error
+			if *kind == KindMapping {
+				live, err := c.MappingTable(ctx, *table)
+				if err != nil {
+					return err
+				}
+				var rows []Row
+				rows, differences, err = Apply(MappingTableRows(live), applied, keys)
+				if err != nil {
+					return err
+				}
+				SetMappingTableRows(&live, rows)
+				update = func() error {
+					_, err := c.MappingTableUpdate(ctx, live)
+					return err
+				}
+			} else {
+				live, err := c.CodeTable(ctx, *table)
+				if err != nil {
+					return err
+				}
+				var rows []Row
+				rows, differences, err = Apply(CodeTableRows(live), applied, keys)
+				if err != nil {
+					return err
+				}
+				SetCodeTableRows(&live, rows)
+				update = func() error {
+					_, err := c.CodeTableUpdate(ctx, live)
+					return err
+				}
+			}
+			updated := false
+			if !*dryrun && len(differences) != 0 {
+				err = update()
+				if err != nil {
+					return fmt.Errorf("updating table %v failed: %w", *table, err)
+				}
+				updated = true
+			}
+			w := csv.NewWriter(os.Stdout)
+			err = w.Write([]string{"Table", "Key", "Change", "Field", "Before", "After", "Changed in Alma"})
+			if err != nil {
+				return fmt.Errorf("error writing csv header: %w", err)
+			}
+			for _, difference := range differences {
+				line := []string{*table, difference.Key, difference.Kind, difference.Field, difference.Before, difference.After}
+				if updated {
+					line = append(line, "yes")
+				} else {
+					line = append(line, "no")
+				}
+				err := w.Write(line)
+				if err != nil {
+					return fmt.Errorf("error writing line to csv: %w", err)
+				}
+			}
+			w.Flush()
+			err = w.Error()
+			if err != nil {
+				return fmt.Errorf("error after flushing csv: %w", err)
+			}
+			log.Printf("%v difference(s) found between %v and the live table.\n", len(differences), *file)
+			return nil
+		},
+	}
+}
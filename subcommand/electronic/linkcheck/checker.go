@@ -0,0 +1,186 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultRate is the default maximum number of links checked per second.
+	DefaultRate = 5
+
+	// DefaultTimeout is the default amount of time a link has to respond.
+	DefaultTimeout = 20 * time.Second
+
+	// DefaultMaxRedirects is the default number of redirects followed before a link is considered broken.
+	DefaultMaxRedirects = 10
+
+	// bodyLimit is the most of a response body which is read, so the connection can be reused.
+	bodyLimit = 64 * 1024
+)
+
+// The outcomes of link checks.
+const (
+	OutcomeOK         = "OK"
+	OutcomeRedirected = "Redirected"
+	OutcomeBroken     = "Broken"
+	OutcomeTimeout    = "Timeout"
+	OutcomeError      = "Error"
+)
+
+// Result is the result of checking a link.
+type Result struct {
+	URL string
+	// StatusCode is the HTTP status code of the last response, or zero if there was no response.
+	StatusCode int
+	// FinalURL is the URL of the last response, after redirects were followed.
+	FinalURL  string
+	Redirects int
+	TimedOut  bool
+	Err       error
+	Duration  time.Duration
+}
+
+// Outcome summarizes the result as OutcomeOK, OutcomeRedirected, OutcomeBroken, OutcomeTimeout, or OutcomeError.
+func (r Result) Outcome() string {
+	switch {
+	case r.TimedOut:
+		return OutcomeTimeout
+	case r.Err != nil:
+		return OutcomeError
+	case r.StatusCode < 200 || r.StatusCode > 299:
+		return OutcomeBroken
+	case r.Redirects > 0:
+		return OutcomeRedirected
+	default:
+		return OutcomeOK
+	}
+}
+
+// Checker checks links. Implementations must be safe for concurrent use.
+type Checker interface {
+	Check(ctx context.Context, url string) Result
+}
+
+// HTTPChecker checks links with GET requests.
+// It has its own rate limiter, so checking links doesn't use up the rate limit of the Alma API client.
+type HTTPChecker struct {
+	// Client is used to make the requests. Its CheckRedirect function is replaced.
+	Client *http.Client
+	// Limiter limits the rate links are checked at. If it is nil, there is no limit.
+	Limiter *rate.Limiter
+	// Timeout is the amount of time a link has to respond, including redirects.
+	Timeout time.Duration
+	// MaxRedirects is the number of redirects followed. Links which redirect more than this are broken.
+	MaxRedirects int
+	// UserAgent is sent with each request, if it isn't empty.
+	UserAgent string
+}
+
+// NewHTTPChecker returns a new HTTPChecker which checks at most callsPerSecond links per second.
+func NewHTTPChecker(callsPerSecond int, timeout time.Duration) *HTTPChecker {
+	return &HTTPChecker{
+		Client:       &http.Client{},
+		Limiter:      rate.NewLimiter(rate.Limit(callsPerSecond), 1),
+		Timeout:      timeout,
+		MaxRedirects: DefaultMaxRedirects,
+		UserAgent:    "almatoolkit-linkcheck",
+	}
+}
+
+// Check makes a GET request to the URL, following redirects.
+func (h *HTTPChecker) Check(ctx context.Context, url string) (result Result) {
+	result.URL = url
+	if h.Limiter != nil {
+		err := h.Limiter.Wait(ctx)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	// The time waiting on the limiter isn't part of the link's response time.
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	r, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	r = r.WithContext(ctx)
+	if h.UserAgent != "" {
+		r.Header.Set("User-Agent", h.UserAgent)
+	}
+	// A copy of the client counts the redirects of this request.
+	client := *h.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		result.Redirects = len(via)
+		if len(via) > h.MaxRedirects {
+			// Stop following redirects, the last response is reported.
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		result.Err = err
+		result.TimedOut = isTimeout(err)
+		return result
+	}
+	// Read some of the body so the connection can be reused, then close it.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, bodyLimit))
+	_ = resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	result.FinalURL = resp.Request.URL.String()
+	return result
+}
+
+// isTimeout returns true if the error was caused by a timeout.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// CheckAll checks the links concurrently with the number of workers, and returns the results in the same order as the URLs.
+func CheckAll(ctx context.Context, checker Checker, urls []string, workers int) (results []Result) {
+	results = make([]Result, len(urls))
+	jobs := make(chan func())
+	wg := &sync.WaitGroup{}
+	api.StartWorkers(wg, jobs, workers)
+	bar := api.DefaultProgressBar(len(urls))
+	bar.Describe("Checking links")
+	for i, url := range urls {
+		i, url := i, url // avoid closure refering to wrong value
+		jobs <- func() {
+			// Each job writes to its own element of the results, so no lock is needed.
+			results[i] = checker.Check(ctx, url)
+		}
+		// Ignore the possible error returned by the progress bar.
+		_ = bar.Add(1)
+	}
+	api.StopConcurrent(jobs, wg)
+	return results
+}
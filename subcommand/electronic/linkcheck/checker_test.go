@@ -0,0 +1,130 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestHTTPChecker checks links against a local server, so no network is needed.
+func TestHTTPChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte("ok"))
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	checker := NewHTTPChecker(1000, 200*time.Millisecond)
+	checker.MaxRedirects = 3
+	for _, test := range []struct {
+		path       string
+		outcome    string
+		statusCode int
+		redirects  int
+	}{
+		{"/ok", OutcomeOK, 200, 0},
+		{"/moved", OutcomeRedirected, 200, 1},
+		{"/missing", OutcomeBroken, 404, 0},
+		{"/loop", OutcomeBroken, 302, 4},
+		{"/slow", OutcomeTimeout, 0, 0},
+	} {
+		result := checker.Check(context.Background(), ts.URL+test.path)
+		if result.Outcome() != test.outcome || result.StatusCode != test.statusCode || result.Redirects != test.redirects {
+			t.Fatalf("unexpected result for %v: %v %+v", test.path, result.Outcome(), result)
+		}
+	}
+	result := checker.Check(context.Background(), ts.URL+"/moved")
+	if result.FinalURL != ts.URL+"/ok" {
+		t.Fatalf("unexpected final URL %v", result.FinalURL)
+	}
+	result = checker.Check(context.Background(), "http://127.0.0.1:0/")
+	if result.Outcome() != OutcomeError {
+		t.Fatalf("expected an error, got %+v", result)
+	}
+}
+
+// TestCheckDurationAfterLimiter checks that the time waiting on the limiter isn't counted in the duration of a check.
+func TestCheckDurationAfterLimiter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	// The second check waits half a second on the limiter.
+	checker := NewHTTPChecker(2, time.Second)
+	checker.Check(context.Background(), ts.URL)
+	result := checker.Check(context.Background(), ts.URL)
+	if result.Outcome() != OutcomeOK || result.Duration >= 250*time.Millisecond {
+		t.Fatalf("unexpected result %v %+v", result.Outcome(), result)
+	}
+}
+
+// fakeChecker reports links containing "broken" as broken.
+type fakeChecker struct{}
+
+func (fakeChecker) Check(ctx context.Context, url string) Result {
+	if strings.Contains(url, "broken") {
+		return Result{URL: url, StatusCode: 404}
+	}
+	return Result{URL: url, StatusCode: 200}
+}
+
+// TestCheckAll checks that results are returned in the same order as the URLs.
+func TestCheckAll(t *testing.T) {
+	urls := []string{}
+	for i := 0; i < 50; i++ {
+		if i%7 == 0 {
+			urls = append(urls, "https://example.com/broken/"+string(rune('a'+i%26)))
+		} else {
+			urls = append(urls, "https://example.com/"+string(rune('a'+i%26)))
+		}
+	}
+	results := CheckAll(context.Background(), fakeChecker{}, urls, 4)
+	for i, result := range results {
+		if result.URL != urls[i] {
+			t.Fatalf("expected result %v to be for %v, got %v", i, urls[i], result.URL)
+		}
+		if (result.Outcome() == OutcomeBroken) != (i%7 == 0) {
+			t.Fatalf("unexpected outcome %v for %v", result.Outcome(), result.URL)
+		}
+	}
+}
+
+// TestStaticURL checks that dynamic URLs are skipped and the jkey= prefix is removed.
+func TestStaticURL(t *testing.T) {
+	portfolio := api.Portfolio{}
+	portfolio.LinkingDetails.URL = "jkey=https://example.com/journal"
+	if url := StaticURL(portfolio); url != "https://example.com/journal" {
+		t.Fatalf("unexpected URL %v", url)
+	}
+	portfolio.LinkingDetails.StaticURLOverride = "jkey=https://example.com/override"
+	if url := StaticURL(portfolio); url != "https://example.com/override" {
+		t.Fatalf("unexpected URL %v", url)
+	}
+	portfolio = api.Portfolio{}
+	portfolio.LinkingDetails.URL = "https://example.com/$$LINKING_LEVEL"
+	if url := StaticURL(portfolio); url != "" {
+		t.Fatalf("expected no URL, got %v", url)
+	}
+}
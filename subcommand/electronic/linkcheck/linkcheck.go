@@ -0,0 +1,142 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package linkcheck provides a subcommand which checks the static URLs of portfolios.
+package linkcheck
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("electronic-linkcheck", flag.ExitOnError)
+	source := subcommand.NewMemberSource(fs, subcommand.PortfolioContent)
+	linkRate := fs.Int("rate", DefaultRate, "The maximum number of links checked per second. This is separate from the Alma API rate limit.")
+	checkers := fs.Int("checkers", 2*DefaultRate, "The number of links checked at the same time.")
+	timeout := fs.Duration("timeout", DefaultTimeout, "The amount of time a link has to respond, including redirects.")
+	problems := fs.Bool("problems", false, "Only report links which are broken, timed out, or redirected.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	fs.Usage = func() {
+		description := "Check the static URLs of the portfolios in a set, reporting the HTTP status code,\n" +
+			"where the link redirects to, and whether it timed out. Portfolios with dynamic URLs are not checked."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.BibsRead},
+		// Each portfolio takes a call to get it. Checking links doesn't call the Alma API.
+		Estimate: source.Estimate(1),
+		FlagSet:  fs,
+		ValidateFlags: func() error {
+			if *linkRate < 1 || *checkers < 1 {
+				return fmt.Errorf("the rate and checkers must be at least 1")
+			}
+			err := report.ValidateFormat(*format)
+			if err != nil {
+				return err
+			}
+			return source.Validate()
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			members, err := source.Members(ctx, c)
+			if err != nil {
+				return err
+			}
+			portfolios, errs := c.PortfolioMembersPortfolios(ctx, members)
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving the portfolios of %v", len(errs), source)
+			}
+			// Portfolios can share a URL, each URL is only checked once.
+			urls := []string{}
+			seen := map[string]bool{}
+			for _, portfolio := range portfolios {
+				if url := StaticURL(portfolio); url != "" && !seen[url] {
+					urls = append(urls, url)
+					seen[url] = true
+				}
+			}
+			results := CheckAll(ctx, NewHTTPChecker(*linkRate, *timeout), urls, *checkers)
+			resultsMap := map[string]Result{}
+			for _, result := range results {
+				resultsMap[result.URL] = result
+			}
+			w, err := report.NewWriter(os.Stdout, *format, []string{"Portfolio ID", "MMS ID", "Title", "URL", "Outcome",
+				"Status Code", "Redirects", "Final URL", "Seconds", "Error"})
+			if err != nil {
+				return err
+			}
+			counts := map[string]int{}
+			for _, portfolio := range portfolios {
+				line := []string{portfolio.ID, portfolio.ResourceMetadata.MMSID.Value, portfolio.ResourceMetadata.Title}
+				url := StaticURL(portfolio)
+				if url == "" {
+					counts["Not checked"]++
+					if *problems {
+						continue
+					}
+					line = append(line, "", "Not checked", "", "", "", "", "the portfolio has no static URL")
+				} else {
+					result := resultsMap[url]
+					counts[result.Outcome()]++
+					if *problems && result.Outcome() == OutcomeOK {
+						continue
+					}
+					status, errMessage := "", ""
+					if result.StatusCode != 0 {
+						status = strconv.Itoa(result.StatusCode)
+					}
+					if result.Err != nil {
+						errMessage = result.Err.Error()
+					}
+					line = append(line, url, result.Outcome(), status, strconv.Itoa(result.Redirects), result.FinalURL,
+						fmt.Sprintf("%.2f", result.Duration.Seconds()), errMessage)
+				}
+				err := w.Write(line)
+				if err != nil {
+					return err
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				return err
+			}
+			summary := []string{}
+			for _, outcome := range []string{OutcomeOK, OutcomeRedirected, OutcomeBroken, OutcomeTimeout, OutcomeError, "Not checked"} {
+				summary = append(summary, fmt.Sprintf("%v %v", counts[outcome], outcome))
+			}
+			log.Printf("%v portfolio(s) checked: %v.\n", len(portfolios), strings.Join(summary, ", "))
+			return nil
+		},
+	}
+}
+
+// StaticURL returns the static URL of the portfolio, without Alma's jkey= prefix.
+// It returns an empty string if the portfolio uses a dynamic URL.
+func StaticURL(portfolio api.Portfolio) string {
+	for _, url := range []string{portfolio.LinkingDetails.StaticURLOverride, portfolio.LinkingDetails.StaticURL, portfolio.LinkingDetails.URL} {
+		url = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(url), "jkey="))
+		// Dynamic URLs are templates filled in by Alma's link resolver, like $$LINKING_LEVEL.
+		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+			if strings.Contains(url, "$$") {
+				return ""
+			}
+			return url
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package portfoliosupdate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// Backup is the JSON of a portfolio before it was changed, one per line of a backup file.
+type Backup struct {
+	Link      string          `json:"link"`
+	Portfolio json.RawMessage `json:"portfolio"`
+}
+
+// WriteBackups writes the raw JSON of the portfolios to w, one portfolio per line.
+func WriteBackups(w io.Writer, portfolios []api.Portfolio) error {
+	e := json.NewEncoder(w)
+	for _, portfolio := range portfolios {
+		err := e.Encode(Backup{portfolio.Link, portfolio.Raw})
+		if err != nil {
+			return fmt.Errorf("writing the backup of portfolio %v failed: %w", portfolio.ID, err)
+		}
+	}
+	return nil
+}
+
+// ReadBackups reads the backups written by WriteBackups.
+func ReadBackups(r io.Reader) (backups []Backup, err error) {
+	s := bufio.NewScanner(r)
+	// Portfolios with long notes can be longer than the default maximum line length.
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		backup := Backup{}
+		err := json.Unmarshal(s.Bytes(), &backup)
+		if err != nil {
+			return backups, fmt.Errorf("line %v of the backup is not valid: %w", line, err)
+		}
+		if backup.Link == "" || len(backup.Portfolio) == 0 {
+			return backups, fmt.Errorf("line %v of the backup has no link or portfolio", line)
+		}
+		backups = append(backups, backup)
+	}
+	err = s.Err()
+	if err != nil {
+		return backups, fmt.Errorf("reading the backup failed: %w", err)
+	}
+	return backups, nil
+}
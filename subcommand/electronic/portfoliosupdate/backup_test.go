@@ -0,0 +1,43 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package portfoliosupdate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestBackups checks that backups are read back exactly as they were written.
+func TestBackups(t *testing.T) {
+	portfolios := []api.Portfolio{
+		{Link: "/almaws/v1/bibs/1/portfolios/2", Raw: []byte(`{"id":"2","unknown":{"big":12345678901234567890}}`)},
+		{Link: "/almaws/v1/bibs/3/portfolios/4", Raw: []byte(`{"id":"4"}`)},
+	}
+	b := &bytes.Buffer{}
+	err := WriteBackups(b, portfolios)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, err := ReadBackups(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != len(portfolios) {
+		t.Fatalf("expected %v backups, got %v", len(portfolios), len(backups))
+	}
+	for i, portfolio := range portfolios {
+		if backups[i].Link != portfolio.Link || string(backups[i].Portfolio) != string(portfolio.Raw) {
+			t.Fatalf("unexpected backup %v %s", backups[i].Link, backups[i].Portfolio)
+		}
+	}
+	_, err = ReadBackups(strings.NewReader(`{"link":"/almaws/v1/bibs/1/portfolios/2"}`))
+	if err == nil {
+		t.Fatal("expected an error for a backup with no portfolio")
+	}
+}
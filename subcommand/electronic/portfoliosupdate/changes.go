@@ -0,0 +1,107 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package portfoliosupdate
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// Changes are the changes made to each portfolio. Zero values make no change.
+type Changes struct {
+	// URLPattern is matched against the URLs of the portfolio, and matches are replaced with URLReplacement.
+	URLPattern     *regexp.Regexp
+	URLReplacement string
+	// Availability is api.Available or api.NotAvailable.
+	Availability string
+	// Coverage replaces the local date coverage of the portfolio.
+	Coverage *api.DateCoverage
+	// CoverageInUse is the code of the coverage the portfolio uses.
+	CoverageInUse string
+}
+
+// Empty returns true if the changes would not change any portfolio.
+func (c Changes) Empty() bool {
+	return c.URLPattern == nil && c.Availability == "" && c.Coverage == nil && c.CoverageInUse == ""
+}
+
+// Apply makes the changes to the portfolio, and returns true if it changed.
+func (c Changes) Apply(portfolio *api.Portfolio) (changed bool) {
+	if c.URLPattern != nil {
+		for _, u := range []*string{&portfolio.LinkingDetails.URL, &portfolio.LinkingDetails.StaticURL, &portfolio.LinkingDetails.StaticURLOverride} {
+			updated := c.URLPattern.ReplaceAllString(*u, c.URLReplacement)
+			if updated != *u {
+				*u = updated
+				changed = true
+			}
+		}
+	}
+	if c.Availability != "" && portfolio.Availability.Value != c.Availability {
+		portfolio.Availability = api.Value{Value: c.Availability}
+		changed = true
+	}
+	if c.Coverage != nil {
+		current := portfolio.CoverageDetails.LocalDateCoverage
+		if current == nil || len(*current) != 1 || (*current)[0] != *c.Coverage {
+			portfolio.CoverageDetails.LocalDateCoverage = &[]api.DateCoverage{*c.Coverage}
+			changed = true
+		}
+	}
+	if c.CoverageInUse != "" && portfolio.CoverageDetails.CoverageInUse.Value != c.CoverageInUse {
+		portfolio.CoverageDetails.CoverageInUse = api.Value{Value: c.CoverageInUse}
+		changed = true
+	}
+	return changed
+}
+
+// ParseCoverage returns the date coverage from and until the dates, which are formatted like 2006, 2006-01, or 2006-01-02.
+// An empty until date means the coverage is open ended.
+func ParseCoverage(from, until string) (coverage api.DateCoverage, err error) {
+	coverage.FromYear, coverage.FromMonth, coverage.FromDay, err = parseCoverageDate(from)
+	if err != nil {
+		return coverage, err
+	}
+	if until != "" {
+		coverage.UntilYear, coverage.UntilMonth, coverage.UntilDay, err = parseCoverageDate(until)
+		if err != nil {
+			return coverage, err
+		}
+		// Only compare as much of the dates as both include, so until 2006 is not before from 2006-05.
+		n := len(from)
+		if len(until) < n {
+			n = len(until)
+		}
+		if until[:n] < from[:n] {
+			return coverage, fmt.Errorf("the coverage until date %v is before the from date %v", until, from)
+		}
+	}
+	return coverage, nil
+}
+
+// parseCoverageDate returns the year, and the month and day if they are included in the date.
+func parseCoverageDate(date string) (year, month, day api.Decimal, err error) {
+	for _, layout := range []string{"2006", "2006-01", "2006-01-02"} {
+		if len(date) != len(layout) {
+			continue
+		}
+		t, err := time.Parse(layout, date)
+		if err != nil {
+			break
+		}
+		year = api.Decimal(fmt.Sprint(t.Year()))
+		if len(layout) > len("2006") {
+			month = api.Decimal(fmt.Sprint(int(t.Month())))
+		}
+		if len(layout) > len("2006-01") {
+			day = api.Decimal(fmt.Sprint(t.Day()))
+		}
+		return year, month, day, nil
+	}
+	return year, month, day, fmt.Errorf("the coverage date '%v' must be formatted like 2006, 2006-01, or 2006-01-02", date)
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package portfoliosupdate
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestApply checks that URLs are rewritten, and that portfolios which already match are not changed.
+func TestApply(t *testing.T) {
+	coverage, err := ParseCoverage("2001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := Changes{
+		URLPattern:     regexp.MustCompile(`^(jkey=)?https?://old\.example\.com/`),
+		URLReplacement: "${1}https://new.example.com/",
+		Availability:   api.Available,
+		Coverage:       &coverage,
+	}
+	portfolio := api.Portfolio{}
+	portfolio.LinkingDetails.URL = "jkey=http://old.example.com/journal/1"
+	portfolio.LinkingDetails.StaticURL = "jkey=http://old.example.com/journal/1"
+	portfolio.Availability.Value = api.NotAvailable
+	if !changes.Apply(&portfolio) {
+		t.Fatal("expected the portfolio to change")
+	}
+	if portfolio.LinkingDetails.URL != "jkey=https://new.example.com/journal/1" || portfolio.LinkingDetails.StaticURL != portfolio.LinkingDetails.URL {
+		t.Fatalf("unexpected URLs %+v", portfolio.LinkingDetails)
+	}
+	if portfolio.Availability.Value != api.Available || Coverage(portfolio) != "2001 to " {
+		t.Fatalf("unexpected availability %v or coverage %v", portfolio.Availability.Value, Coverage(portfolio))
+	}
+	if changes.Apply(&portfolio) {
+		t.Fatal("expected the changes to have already been made")
+	}
+}
+
+// TestParseCoverage checks the coverage dates.
+func TestParseCoverage(t *testing.T) {
+	coverage, err := ParseCoverage("2001-03", "2010-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := api.DateCoverage{FromYear: "2001", FromMonth: "3", UntilYear: "2010", UntilMonth: "12", UntilDay: "31"}
+	if coverage != expected {
+		t.Fatalf("unexpected coverage %+v", coverage)
+	}
+	_, err = ParseCoverage("2006", "2006-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dates := range [][2]string{{"2010", "2001"}, {"2001-13", ""}, {"01/02/2006", ""}, {"", ""}} {
+		_, err := ParseCoverage(dates[0], dates[1])
+		if err == nil {
+			t.Fatalf("expected an error for %v", dates)
+		}
+	}
+}
+
+// TestApplyCoverageReplacesUntil checks that open ended coverage clears the until date of the live coverage when the portfolio is updated.
+func TestApplyCoverageReplacesUntil(t *testing.T) {
+	raw := `{"id":"53","coverage_details":{"coverage_in_use":{"value":"LOCAL"},"local_date_coverage_parameters":` +
+		`[{"from_year":"2001","from_month":"3","until_year":"2010","until_month":"12","from_volume":"4"}]},"availability":{"value":"11"}}`
+	var sent map[string]interface{}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = json.Unmarshal(body, &sent)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &api.Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+	portfolio := api.Portfolio{Link: "/almaws/v1/bibs/99/portfolios/53", Raw: []byte(raw)}
+	err = json.Unmarshal([]byte(raw), &portfolio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coverage, err := ParseCoverage("2005", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(Changes{Coverage: &coverage}).Apply(&portfolio) {
+		t.Fatal("expected the portfolio to change")
+	}
+	updated, err := c.PortfolioUpdate(context.Background(), portfolio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, ok := sent["coverage_details"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected coverage details %v", sent["coverage_details"])
+	}
+	want := []interface{}{map[string]interface{}{"from_year": float64(2005)}}
+	if !reflect.DeepEqual(details["local_date_coverage_parameters"], want) {
+		t.Fatalf("unexpected coverage sent\ngot:  %v\nwant: %v", details["local_date_coverage_parameters"], want)
+	}
+	if inUse, ok := details["coverage_in_use"].(map[string]interface{}); !ok || inUse["value"] != "LOCAL" {
+		t.Fatalf("coverage in use was not kept: %v", details)
+	}
+	if Coverage(updated) != "2005 to " {
+		t.Fatalf("unexpected updated coverage %v", Coverage(updated))
+	}
+}
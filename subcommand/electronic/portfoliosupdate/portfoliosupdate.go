@@ -0,0 +1,295 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package portfoliosupdate provides a subcommand which updates the URLs, availability, and coverage of portfolios.
+package portfoliosupdate
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("electronic-portfolios-update", flag.ExitOnError)
+	source := subcommand.NewMemberSource(fs, subcommand.PortfolioContent)
+	urlPattern := fs.String("urlpattern", "", "A regular expression matched against the URLs of the portfolios, like ^https?://old\\.example\\.com/.\n"+
+		"See https://golang.org/pkg/regexp/syntax/ for the syntax.")
+	urlReplace := fs.String("urlreplace", "", "The replacement for matches of urlpattern. $1 is replaced by the first submatch, and so on.")
+	availability := fs.String("availability", "", "Make the portfolios 'available' or 'unavailable'.")
+	coverageFrom := fs.String("coveragefrom", "", "Replace the local date coverage of the portfolios, starting on this date, like 2006, 2006-01, or 2006-01-02.")
+	coverageUntil := fs.String("coverageuntil", "", "The end date of the new local date coverage. Leave it empty for open ended coverage.")
+	coverageInUse := fs.String("coverageinuse", "", "Change which coverage the portfolios use to the code, which must be a value of Alma's coverage in use list.")
+	backup := fs.String("backup", "", "The path of the file the portfolios are backed up to before they are changed.\n"+
+		"Defaults to portfolios-backup-DATE-TIME.jsonl in the current directory.")
+	restore := fs.String("restore", "", "The path of a backup file. The portfolios in it are restored to how they were before they were changed.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	dryrun := fs.Bool("dryrun", false, "Do not perform any updates. Report on what changes would have been made.")
+	fs.Usage = func() {
+		description := "Update the portfolios in a set, rewriting their URLs with a regular expression,\n" +
+			"changing their availability, or replacing their local date coverage.\n" +
+			"The portfolios are backed up before they are changed, and can be restored from the backup with the restore flag."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	changes := Changes{}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead, api.BibsWrite},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			err := report.ValidateFormat(*format)
+			if err != nil {
+				return err
+			}
+			if *restore != "" {
+				if !source.Empty() {
+					return fmt.Errorf("the restore flag can't be used with a set")
+				}
+				return nil
+			}
+			err = source.Validate()
+			if err != nil {
+				return err
+			}
+			if *urlPattern != "" {
+				changes.URLPattern, err = regexp.Compile(*urlPattern)
+				if err != nil {
+					return fmt.Errorf("the url pattern is not a valid regular expression: %w", err)
+				}
+				changes.URLReplacement = *urlReplace
+			} else if *urlReplace != "" {
+				return fmt.Errorf("the urlreplace flag needs a urlpattern")
+			}
+			switch *availability {
+			case "":
+			case "available":
+				changes.Availability = api.Available
+			case "unavailable":
+				changes.Availability = api.NotAvailable
+			default:
+				return fmt.Errorf("the availability must be 'available' or 'unavailable'")
+			}
+			if *coverageFrom != "" {
+				coverage, err := ParseCoverage(*coverageFrom, *coverageUntil)
+				if err != nil {
+					return err
+				}
+				changes.Coverage = &coverage
+			} else if *coverageUntil != "" {
+				return fmt.Errorf("the coverageuntil flag needs a coveragefrom date")
+			}
+			changes.CoverageInUse = *coverageInUse
+			if changes.Empty() {
+				return fmt.Errorf("at least one change to the portfolios is required")
+			}
+			return nil
+		},
+		Estimate: func(ctx context.Context, c *api.Client) (calls int, err error) {
+			if *restore != "" {
+				backups, err := readBackups(*restore)
+				return len(backups), err
+			}
+			// Each portfolio takes a GET and a PUT.
+			return source.Estimate(2)(ctx, c)
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			if *dryrun {
+				log.Println("Running in dry run mode, no changes will be made in Alma.")
+			} else {
+				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+			}
+			if *restore != "" {
+				return runRestore(ctx, c, *restore, *format, *dryrun)
+			}
+			members, err := source.Members(ctx, c)
+			if err != nil {
+				return err
+			}
+			portfolios, errs := c.PortfolioMembersPortfolios(ctx, members)
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving the portfolios of %v", len(errs), source)
+			}
+			originals := []api.Portfolio{}
+			changed := []api.Portfolio{}
+			changedMap := map[string]api.Portfolio{}
+			for _, portfolio := range portfolios {
+				original := portfolio
+				if changes.Apply(&portfolio) {
+					originals = append(originals, original)
+					changed = append(changed, portfolio)
+					changedMap[portfolio.Link] = portfolio
+				}
+			}
+			updated := []api.Portfolio{}
+			errs = []error{}
+			if !*dryrun && len(changed) != 0 {
+				path := *backup
+				if path == "" {
+					path = "portfolios-backup-" + time.Now().Format("20060102-150405") + ".jsonl"
+				}
+				err := writeBackups(path, originals)
+				if err != nil {
+					return err
+				}
+				log.Printf("%v portfolio(s) backed up to %v.\n", len(originals), path)
+				updated, errs = c.PortfoliosUpdate(ctx, changed)
+			}
+			updatedMap := map[string]bool{}
+			for _, portfolio := range updated {
+				updatedMap[portfolio.Link] = true
+			}
+			w, err := report.NewWriter(os.Stdout, *format, []string{"Portfolio ID", "MMS ID", "Title", "Original URL", "Updated URL",
+				"Original Availability", "Updated Availability", "Original Coverage", "Updated Coverage", "Changed in Alma"})
+			if err != nil {
+				return err
+			}
+			for _, portfolio := range portfolios {
+				changedPortfolio, inChanged := changedMap[portfolio.Link]
+				if !inChanged {
+					changedPortfolio = portfolio
+				}
+				inUpdated := "no"
+				if updatedMap[portfolio.Link] {
+					inUpdated = "yes"
+				}
+				err := w.Write([]string{portfolio.ID, portfolio.ResourceMetadata.MMSID.Value, portfolio.ResourceMetadata.Title,
+					portfolio.LinkingDetails.URL, changedPortfolio.LinkingDetails.URL,
+					portfolio.Availability.Value, changedPortfolio.Availability.Value,
+					Coverage(portfolio), Coverage(changedPortfolio), inUpdated})
+				if err != nil {
+					return err
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				return err
+			}
+			log.Printf("%v of %v portfolio(s) changed, %v updated in Alma.\n", len(changed), len(portfolios), len(updated))
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when updating the portfolios of %v", len(errs), source)
+			}
+			return nil
+		},
+	}
+}
+
+// Coverage returns the local date coverage of the portfolio, like "2001-01 to 2010; 2015 to".
+func Coverage(portfolio api.Portfolio) (coverage string) {
+	if portfolio.CoverageDetails.LocalDateCoverage == nil {
+		return coverage
+	}
+	for i, dates := range *portfolio.CoverageDetails.LocalDateCoverage {
+		if i > 0 {
+			coverage += "; "
+		}
+		coverage += joinDate(dates.FromYear, dates.FromMonth, dates.FromDay) + " to " + joinDate(dates.UntilYear, dates.UntilMonth, dates.UntilDay)
+	}
+	return coverage
+}
+
+// joinDate joins the parts of a date which are not empty.
+func joinDate(year, month, day api.Decimal) (date string) {
+	date = string(year)
+	for _, part := range []api.Decimal{month, day} {
+		if part == "" {
+			break
+		}
+		date += fmt.Sprintf("-%02v", part)
+	}
+	return date
+}
+
+// runRestore restores the portfolios in the backup file at the path.
+func runRestore(ctx context.Context, c *api.Client, path, format string, dryrun bool) error {
+	backups, err := readBackups(path)
+	if err != nil {
+		return err
+	}
+	portfolios := []api.Portfolio{}
+	for _, backup := range backups {
+		portfolios = append(portfolios, api.Portfolio{Link: backup.Link, Raw: backup.Portfolio})
+	}
+	restored := []api.Portfolio{}
+	errs := []error{}
+	if !dryrun {
+		restored, errs = c.PortfoliosRestore(ctx, portfolios)
+	}
+	restoredMap := map[string]bool{}
+	for _, portfolio := range restored {
+		restoredMap[portfolio.Link] = true
+	}
+	w, err := report.NewWriter(os.Stdout, format, []string{"Link", "Restored in Alma"})
+	if err != nil {
+		return err
+	}
+	for _, portfolio := range portfolios {
+		inRestored := "no"
+		if restoredMap[portfolio.Link] {
+			inRestored = "yes"
+		}
+		err := w.Write([]string{portfolio.Link, inRestored})
+		if err != nil {
+			return err
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	log.Printf("%v of %v portfolio(s) restored.\n", len(restored), len(portfolios))
+	if len(errs) != 0 {
+		for _, err := range errs {
+			log.Println(err)
+		}
+		return fmt.Errorf("%v error(s) occured when restoring portfolios", len(errs))
+	}
+	return nil
+}
+
+// readBackups reads the backups in the file at the path.
+func readBackups(path string) (backups []Backup, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return backups, err
+	}
+	defer f.Close()
+	return ReadBackups(f)
+}
+
+// writeBackups writes the portfolios to a new backup file at the path.
+func writeBackups(path string, portfolios []api.Portfolio) error {
+	// Don't overwrite an earlier backup.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("creating the backup file failed: %w", err)
+	}
+	bw := bufio.NewWriter(f)
+	err = WriteBackups(bw, portfolios)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing the backup file failed: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,228 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package importjob provides a subcommand which runs an import profile and reports on the records it imported.
+package importjob
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// The outcomes of import job events.
+const (
+	OutcomeImported = "Imported"
+	OutcomeMatched  = "Matched"
+	OutcomeRejected = "Rejected"
+	OutcomeOther    = "Other"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("jobs-import", flag.ExitOnError)
+	profileID := fs.String("profileid", "", "The ID of the import profile. This flag or jobid are required.")
+	jobID := fs.String("jobid", "", "The ID of the import profile's job, instead of the profile ID.")
+	file := fs.String("file", "", "The path of a MARC file to upload to the import profile. Needs the unverifiedupload flag.\n"+
+		"Without it, the profile imports the files waiting on its FTP server.")
+	unverifiedUpload := fs.Bool("unverifiedupload", false, "Allow the file flag. Alma's documentation doesn't describe uploading a file when a job is run,\n"+
+		"so the upload hasn't been verified and may be refused or ignored by Alma.")
+	instanceID := fs.String("instanceid", "", "Report on an instance of the job which already ran, instead of running the job.")
+	interval := fs.Duration("interval", api.DefaultJobPollInterval, "The time to wait between checks on the status of the job.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	dryrun := fs.Bool("dryrun", false, "Do not run the job. Report on the job which would have been run.")
+	fs.Usage = func() {
+		description := "Run an import profile, optionally uploading a MARC file to it, and wait for the import to finish.\n" +
+			"The records which were imported, matched, or rejected are reported from the events of the job."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			err := report.ValidateFormat(*format)
+			if err != nil {
+				return err
+			}
+			if (*profileID == "") == (*jobID == "") {
+				return fmt.Errorf("a profile ID OR a job ID is required")
+			}
+			if *interval < api.DefaultJobPollInterval/10 {
+				return fmt.Errorf("the interval must be at least %v", api.DefaultJobPollInterval/10)
+			}
+			if *instanceID != "" {
+				if *file != "" {
+					return fmt.Errorf("the file flag can't be used with an instance ID")
+				}
+				return nil
+			}
+			if *file != "" {
+				if !*unverifiedUpload {
+					return fmt.Errorf("uploading a file uses an API call which isn't in Alma's documentation, add the unverifiedupload flag to use it")
+				}
+				info, err := os.Stat(*file)
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					return fmt.Errorf("the file %v is a directory", *file)
+				}
+			}
+			return nil
+		},
+		ExtraCapabilities: func() []api.Capability {
+			// Reporting on an instance only reads the configuration, running the job needs read/write permission.
+			if *instanceID != "" {
+				return nil
+			}
+			return []api.Capability{api.ConfWrite}
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			job, err := findJob(ctx, c, *profileID, *jobID)
+			if err != nil {
+				return err
+			}
+			if *instanceID == "" {
+				if *dryrun {
+					log.Println("Running in dry run mode, no changes will be made in Alma.")
+					if *file != "" {
+						log.Printf("The file %v would have been uploaded to the job '%v' (ID %v).\n", *file, job.Name, job.ID)
+					} else {
+						log.Printf("The job '%v' (ID %v) would have been run.\n", job.Name, job.ID)
+					}
+					return nil
+				}
+				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+				*instanceID, err = runJob(ctx, c, job, *file)
+				if err != nil {
+					return err
+				}
+				log.Printf("The job '%v' (ID %v) was started, waiting for instance %v to finish.\n", job.Name, job.ID, *instanceID)
+			}
+			instance, err := c.JobInstanceWait(ctx, job.ID, *instanceID, *interval)
+			if err != nil {
+				log.Printf("Stopped waiting, instance %v of job %v may still be running in Alma. Report on it later with the instanceid flag.\n", *instanceID, job.ID)
+				return err
+			}
+			for _, counter := range instance.Counters {
+				log.Printf("%v: %v\n", counter.Type.Label(), counter.Value)
+			}
+			for _, alert := range instance.Alerts {
+				log.Printf("Alert: %v\n", alert.Label())
+			}
+			// The events of a failed import are still reported, they often explain the failure.
+			var jobErr error
+			if !instance.Succeeded() {
+				jobErr = fmt.Errorf("instance %v of job %v finished with status %v", *instanceID, job.ID, instance.Status.Label())
+			}
+			events, errs := c.JobInstanceEvents(ctx, job.ID, *instanceID)
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when retrieving the events of instance %v of job %v", len(errs), *instanceID, job.ID)
+			}
+			err = writeEvents(events, *format)
+			if err != nil {
+				return err
+			}
+			return jobErr
+		},
+	}
+}
+
+// EventOutcomes maps the types of the events of import jobs to the outcome for the record the event is about.
+// Events are matched on the whole type code, and events with other types are reported as Other with their type,
+// so a code missing from this list shows up in the report instead of being counted as the wrong outcome.
+var EventOutcomes = map[string]string{
+	"MD_IMPORT_RECORD_IMPORTED": OutcomeImported,
+	// Records without a match are added as new records.
+	"MD_IMPORT_NO_MATCH":        OutcomeImported,
+	"MD_IMPORT_RECORD_MATCHED":  OutcomeMatched,
+	"MD_IMPORT_RECORD_MERGED":   OutcomeMatched,
+	"MD_IMPORT_RECORD_REJECTED": OutcomeRejected,
+	"FILE_VALIDATION_FAILED":    OutcomeRejected,
+}
+
+// Outcome returns whether the event is about a record which was imported, matched, or rejected, based on the type of the event.
+func Outcome(event api.JobEvent) string {
+	outcome, found := EventOutcomes[event.Type.Value]
+	if !found {
+		return OutcomeOther
+	}
+	return outcome
+}
+
+// findJob returns the job which runs the import profile, or the job with the ID if no profile ID is provided.
+func findJob(ctx context.Context, c *api.Client, profileID, ID string) (job api.Job, err error) {
+	if ID == "" {
+		jobs, errs := c.Jobs(ctx, api.JobsQuery{ProfileID: profileID})
+		if len(errs) != 0 {
+			for _, err := range errs {
+				log.Println(err)
+			}
+			return job, fmt.Errorf("%v error(s) occured when retrieving the jobs of import profile %v", len(errs), profileID)
+		}
+		if len(jobs) != 1 {
+			return job, fmt.Errorf("import profile %v has %v jobs, use the jobid flag", profileID, len(jobs))
+		}
+		ID = jobs[0].ID
+	}
+	job, err = c.Job(ctx, ID)
+	if err != nil {
+		return job, fmt.Errorf("getting job %v failed: %w", ID, err)
+	}
+	return job, nil
+}
+
+// runJob runs the job on the file, or on the files on the profile's FTP server if the path is empty.
+func runJob(ctx context.Context, c *api.Client, job api.Job, path string) (instanceID string, err error) {
+	if path == "" {
+		return c.JobRun(ctx, job)
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return instanceID, fmt.Errorf("reading the file failed: %w", err)
+	}
+	return c.JobRunFile(ctx, job, filepath.Base(path), contents)
+}
+
+// writeEvents writes a report of the events, one line per record, and logs a count of each outcome.
+func writeEvents(events []api.JobEvent, format string) error {
+	w, err := report.NewWriter(os.Stdout, format, []string{"Outcome", "Event", "Severity", "Record Type", "Record ID", "Description"})
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for _, event := range events {
+		outcome := Outcome(event)
+		counts[outcome]++
+		entities := event.Entities
+		if len(entities) == 0 {
+			entities = []api.JobEventEntity{{}}
+		}
+		for _, entity := range entities {
+			err := w.Write([]string{outcome, event.Type.Label(), event.Severity.Label(), entity.Type.Label(), entity.Value, event.Description})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	log.Printf("%v event(s): %v imported, %v matched, %v rejected, %v other.\n", len(events),
+		counts[OutcomeImported], counts[OutcomeMatched], counts[OutcomeRejected], counts[OutcomeOther])
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package importjob
+
+import (
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestOutcome checks that import events are sorted into imported, matched, and rejected records by their exact type.
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		eventType api.Value
+		want      string
+	}{
+		{api.Value{Value: "MD_IMPORT_RECORD_IMPORTED"}, OutcomeImported},
+		{api.Value{Value: "MD_IMPORT_NO_MATCH", Desc: "No match found, record added"}, OutcomeImported},
+		{api.Value{Value: "MD_IMPORT_RECORD_MATCHED"}, OutcomeMatched},
+		{api.Value{Value: "MD_IMPORT_RECORD_MERGED"}, OutcomeMatched},
+		{api.Value{Value: "MD_IMPORT_RECORD_REJECTED"}, OutcomeRejected},
+		{api.Value{Value: "FILE_VALIDATION_FAILED"}, OutcomeRejected},
+		// Types which aren't listed are Other, even if their code or description looks like an outcome.
+		{api.Value{Value: "MD_IMPORT_MULTI_MATCH"}, OutcomeOther},
+		{api.Value{Value: "E1", Desc: "Record matched and merged"}, OutcomeOther},
+		{api.Value{Value: "md_import_record_imported"}, OutcomeOther},
+		{api.Value{Value: "JOB_STARTED"}, OutcomeOther},
+	}
+	for _, test := range tests {
+		got := Outcome(api.JobEvent{Type: test.eventType})
+		if got != test.want {
+			t.Errorf("Outcome(%+v) = %v, want %v", test.eventType, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package run provides a subcommand which runs a manual Alma job and waits for it to finish.
+package run
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+	"github.com/cu-library/almatoolkit/subcommand/report"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("jobs-run", flag.ExitOnError)
+	jobID := fs.String("jobid", "", "The ID of the manual job to run, like M18. This flag or jobname are required, unless listing jobs.")
+	jobName := fs.String("jobname", "", "The name of the manual job to run. This flag or jobid are required, unless listing jobs.")
+	ID := fs.String("setid", "", "The ID of the set the job runs on. Optional, some jobs don't run on a set.")
+	name := fs.String("setname", "", "The name of the set the job runs on. Optional, some jobs don't run on a set.")
+	params := &Parameters{}
+	fs.Var(params, "param", "A parameter of the job, like name=value. Can be repeated. Use dryrun to see the job's parameters.")
+	interval := fs.Duration("interval", api.DefaultJobPollInterval, "The time to wait between checks on the status of the job.")
+	list := fs.Bool("list", false, "List the manual jobs which can be run, instead of running a job.")
+	format := fs.String("format", report.FormatCSV, report.FormatUsage)
+	dryrun := fs.Bool("dryrun", false, "Do not run the job. Report on the parameters the job would have been run with.")
+	fs.Usage = func() {
+		description := "Run a manual Alma job, like 'Change Physical items information', optionally on a set.\n" +
+			"The subcommand waits for the job to finish, reports the job's counters, and fails if the job failed."
+		subcommand.Usage(fs, envPrefix, description)
+	}
+	return &subcommand.Config{
+		Capabilities: []api.Capability{api.ConfRead},
+		FlagSet:      fs,
+		ValidateFlags: func() error {
+			err := report.ValidateFormat(*format)
+			if err != nil {
+				return err
+			}
+			if *list {
+				if *jobID != "" || *jobName != "" || *ID != "" || *name != "" || len(*params) != 0 {
+					return fmt.Errorf("the list flag can't be used with a job, set, or parameters")
+				}
+				return nil
+			}
+			if (*jobID == "") == (*jobName == "") {
+				return fmt.Errorf("a job ID OR a job name is required")
+			}
+			if *ID != "" && *name != "" {
+				return fmt.Errorf("a set name OR a set ID can be provided, not both")
+			}
+			if *interval < api.DefaultJobPollInterval/10 {
+				return fmt.Errorf("the interval must be at least %v", api.DefaultJobPollInterval/10)
+			}
+			return nil
+		},
+		ExtraCapabilities: func() []api.Capability {
+			// Listing jobs only reads the configuration, running one needs read/write permission.
+			if *list {
+				return nil
+			}
+			return []api.Capability{api.ConfWrite}
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			if *list {
+				return listJobs(ctx, c, *format)
+			}
+			if *dryrun {
+				log.Println("Running in dry run mode, no changes will be made in Alma.")
+			} else {
+				log.Println("WARNING: Not running in dry run mode, changes will be made in Alma!")
+			}
+			job, err := findJob(ctx, c, *jobID, *jobName)
+			if err != nil {
+				return err
+			}
+			if *ID != "" || *name != "" {
+				set, err := c.SetFromNameOrID(ctx, *name, *ID)
+				if err != nil {
+					return err
+				}
+				log.Printf("Running on the set '%v' (ID %v) with %v member(s).\n", set.Name, set.ID, set.NumberOfMembers)
+				job.SetParameter("set_id", set.ID)
+			}
+			for _, param := range *params {
+				job.SetParameter(param.Name.Value, param.Value)
+			}
+			for _, param := range job.Parameters {
+				log.Printf("Parameter %v = %v\n", param.Name.Value, param.Value)
+			}
+			if *dryrun {
+				log.Printf("The job '%v' (ID %v) would have been run.\n", job.Name, job.ID)
+				return nil
+			}
+			instanceID, err := c.JobRun(ctx, job)
+			if err != nil {
+				return err
+			}
+			log.Printf("The job '%v' (ID %v) was started, waiting for instance %v to finish.\n", job.Name, job.ID, instanceID)
+			instance, err := c.JobInstanceWait(ctx, job.ID, instanceID, *interval)
+			if err != nil {
+				log.Printf("Stopped waiting, instance %v of job %v may still be running in Alma.\n", instanceID, job.ID)
+				return err
+			}
+			err = writeCounters(instance, *format)
+			if err != nil {
+				return err
+			}
+			for _, alert := range instance.Alerts {
+				log.Printf("Alert: %v\n", alert.Label())
+			}
+			if !instance.Succeeded() {
+				return fmt.Errorf("instance %v of job %v finished with status %v", instanceID, job.ID, instance.Status.Label())
+			}
+			log.Printf("Instance %v of job %v finished with status %v.\n", instanceID, job.ID, instance.Status.Label())
+			return nil
+		},
+	}
+}
+
+// Parameters collects the job parameters from repeated param flags.
+type Parameters []api.JobParameter
+
+// String returns the parameters like name=value, separated by commas.
+func (p *Parameters) String() string {
+	params := []string{}
+	for _, param := range *p {
+		params = append(params, param.Name.Value+"="+param.Value)
+	}
+	return strings.Join(params, ",")
+}
+
+// Set adds a parameter, formatted like name=value.
+func (p *Parameters) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("the parameter '%v' must be formatted like name=value", s)
+	}
+	*p = append(*p, api.JobParameter{Name: api.Value{Value: strings.TrimSpace(parts[0])}, Value: parts[1]})
+	return nil
+}
+
+// findJob returns the manual job with the ID, or the name if no ID is provided.
+func findJob(ctx context.Context, c *api.Client, ID, name string) (job api.Job, err error) {
+	if ID == "" {
+		jobs, errs := c.Jobs(ctx, api.JobsQuery{Type: "MANUAL"})
+		if len(errs) != 0 {
+			for _, err := range errs {
+				log.Println(err)
+			}
+			return job, fmt.Errorf("%v error(s) occured when retrieving jobs", len(errs))
+		}
+		matches := []string{}
+		for _, j := range jobs {
+			if strings.EqualFold(strings.TrimSpace(j.Name), strings.TrimSpace(name)) {
+				matches = append(matches, j.ID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return job, fmt.Errorf("no manual job named '%v' found, use the list flag to see the manual jobs", name)
+		case 1:
+			ID = matches[0]
+		default:
+			return job, fmt.Errorf("the jobs %v are all named '%v', use the jobid flag", strings.Join(matches, ", "), name)
+		}
+	}
+	// The job has to be retrieved by ID to get its parameters.
+	job, err = c.Job(ctx, ID)
+	if err != nil {
+		return job, fmt.Errorf("getting job %v failed: %w", ID, err)
+	}
+	if job.Type.Value != "MANUAL" {
+		return job, fmt.Errorf("job %v is a %v job, only manual jobs can be run", ID, job.Type.Label())
+	}
+	return job, nil
+}
+
+// listJobs writes a report of the manual jobs.
+func listJobs(ctx context.Context, c *api.Client, format string) error {
+	jobs, errs := c.Jobs(ctx, api.JobsQuery{Type: "MANUAL"})
+	if len(errs) != 0 {
+		for _, err := range errs {
+			log.Println(err)
+		}
+		return fmt.Errorf("%v error(s) occured when retrieving jobs", len(errs))
+	}
+	w, err := report.NewWriter(os.Stdout, format, []string{"ID", "Name", "Category", "Content", "Description"})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		err := w.Write([]string{job.ID, job.Name, job.Category.Label(), job.Content.Label(), job.Description})
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// writeCounters writes a report of the counters of the job instance.
+func writeCounters(instance api.JobInstance, format string) error {
+	w, err := report.NewWriter(os.Stdout, format, []string{"Counter", "Value"})
+	if err != nil {
+		return err
+	}
+	for _, counter := range instance.Counters {
+		err := w.Write([]string{counter.Type.Label(), string(counter.Value)})
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package run
+
+import (
+	"flag"
+	"testing"
+)
+
+// TestParameters checks that repeated param flags are collected, and values can contain equals signs.
+func TestParameters(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	params := &Parameters{}
+	fs.Var(params, "param", "")
+	err := fs.Parse([]string{"-param", "job_name=Weeding", "-param", " note = a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*params) != 2 || (*params)[1].Name.Value != "note" || (*params)[1].Value != " a=b" {
+		t.Fatalf("unexpected parameters %+v", *params)
+	}
+	if params.String() != "job_name=Weeding,note= a=b" {
+		t.Fatalf("unexpected string %q", params.String())
+	}
+	if params.Set("=value") == nil || params.Set("novalue") == nil {
+		t.Fatal("expected an error for a parameter without a name or value")
+	}
+}
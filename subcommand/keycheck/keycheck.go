@@ -0,0 +1,79 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package keycheck provides a subcommand which reports the permissions of the API key.
+package keycheck
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cu-library/almatoolkit/api"
+	"github.com/cu-library/almatoolkit/subcommand"
+)
+
+// Config returns a new subcommand config.
+func Config(envPrefix string) *subcommand.Config {
+	fs := flag.NewFlagSet("key-check", flag.ExitOnError)
+	fs.Usage = func() {
+		description := "Report which permissions the API key has in every area of the Alma API.\n" +
+			"Read-only permissions are checked with GET requests to each area's test endpoint,\n" +
+			"read/write permissions with POST requests. No changes are made in Alma."
+		subcommand.UsageNoFlags(fs, description)
+	}
+	return &subcommand.Config{
+		FlagSet: fs,
+		Estimate: func(ctx context.Context, c *api.Client) (int, error) {
+			return len(api.AllCapabilities), nil
+		},
+		Run: func(ctx context.Context, c *api.Client) error {
+			w := csv.NewWriter(os.Stdout)
+			err := w.Write([]string{"Area", "Permission", "Path", "Allowed"})
+			if err != nil {
+				return fmt.Errorf("error writing csv header: %w", err)
+			}
+			errs := []error{}
+			for _, capability := range api.AllCapabilities {
+				allowed := "yes"
+				stop := false
+				err := c.CheckCapability(ctx, capability)
+				var missing *api.MissingCapabilityError
+				if errors.As(err, &missing) {
+					allowed = "no"
+				} else if err != nil {
+					allowed = "unknown"
+					errs = append(errs, err)
+					var thresholdErr *api.ThresholdReachedError
+					stop = errors.As(err, &thresholdErr) || ctx.Err() != nil
+				}
+				err = w.Write([]string{capability.Area, capability.Permission(), capability.Path, allowed})
+				if err != nil {
+					return fmt.Errorf("error writing line to csv: %w", err)
+				}
+				// The row of the capability which failed is written before stopping.
+				if stop {
+					break
+				}
+			}
+			w.Flush()
+			err = w.Error()
+			if err != nil {
+				return fmt.Errorf("error after flushing csv: %w", err)
+			}
+			if len(errs) != 0 {
+				for _, err := range errs {
+					log.Println(err)
+				}
+				return fmt.Errorf("%v error(s) occured when checking the API key", len(errs))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package subcommand
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// The content of sets, which is the kind of member a MemberSource provides.
+const (
+	BibContent       = "BIB_MMS"
+	ItemContent      = "ITEM"
+	PortfolioContent = "IEP"
+)
+
+// The types of ID which can be read from a column of an Analytics report.
+const (
+	MMSIDType   = "mms_id"
+	PIDType     = "pid"
+	BarcodeType = "barcode"
+)
+
+// contentIDTypes are the types of ID which can be turned into members with each content.
+// Portfolios can't be looked up from a single ID, so they can only come from sets.
+var contentIDTypes = map[string][]string{
+	BibContent:  {MMSIDType},
+	ItemContent: {BarcodeType, PIDType},
+}
+
+// contentDescriptions describe the content of sets in error messages.
+var contentDescriptions = map[string]string{
+	BibContent:       "bibs",
+	ItemContent:      "items",
+	PortfolioContent: "portfolios",
+}
+
+// MemberSource is where a subcommand gets the members it processes,
+// either an itemized set or a column of IDs in an Analytics report.
+type MemberSource struct {
+	SetID           string
+	SetName         string
+	AnalyticsPath   string
+	AnalyticsColumn string
+	IDType          string
+	// Content is the content of the members, like ItemContent.
+	Content string
+	// description describes where the members came from, once they are retrieved.
+	description string
+	// The IDs from the Analytics report and the number of pages they took are kept once the report has run,
+	// so the report only runs once when the calls are estimated before the members are retrieved.
+	reportRan bool
+	reportIDs []string
+	pages     int
+}
+
+// NewMemberSource adds the setid and setname flags to the flag set, and the analytics-path,
+// analytics-column, and analytics-idtype flags if members with the content can come from an Analytics report.
+func NewMemberSource(fs *flag.FlagSet, content string) *MemberSource {
+	m := &MemberSource{Content: content}
+	idTypes := contentIDTypes[content]
+	if len(idTypes) == 0 {
+		fs.StringVar(&m.SetID, "setid", "", "The ID of the set we are processing. This flag or setname are required.")
+		fs.StringVar(&m.SetName, "setname", "", "The name of the set we are processing. This flag or setid are required.")
+		return m
+	}
+	fs.StringVar(&m.SetID, "setid", "", "The ID of the set we are processing. This flag, setname, or analytics-path are required.")
+	fs.StringVar(&m.SetName, "setname", "", "The name of the set we are processing. This flag, setid, or analytics-path are required.")
+	fs.StringVar(&m.AnalyticsPath, "analytics-path", "", "The path of an Analytics report with a column of IDs to process instead of a set, "+
+		"like '/shared/Carleton University/Reports/Items'.")
+	fs.StringVar(&m.AnalyticsColumn, "analytics-column", "", "The heading of the column of IDs in the Analytics report. Defaults to the first column.")
+	fs.StringVar(&m.IDType, "analytics-idtype", idTypes[0], "The type of ID in the Analytics report column, "+strings.Join(idTypes, " or ")+".")
+	return m
+}
+
+// Empty returns true if none of the source flags were set.
+func (m *MemberSource) Empty() bool {
+	return m.SetID == "" && m.SetName == "" && m.AnalyticsPath == ""
+}
+
+// Analytics returns true if the members come from an Analytics report.
+func (m *MemberSource) Analytics() bool {
+	return m.AnalyticsPath != ""
+}
+
+// Validate ensures exactly one source was provided, and the ID type can be used with the content.
+func (m *MemberSource) Validate() error {
+	if !m.Analytics() {
+		if m.SetName == "" && m.SetID == "" && len(contentIDTypes[m.Content]) != 0 {
+			return fmt.Errorf("a set name, a set ID, or an Analytics report path are required")
+		}
+		return ValidateSetNameAndSetIDFlags(m.SetName, m.SetID)
+	}
+	if m.SetName != "" || m.SetID != "" {
+		return fmt.Errorf("a set OR an Analytics report can be provided, not both")
+	}
+	for _, idType := range contentIDTypes[m.Content] {
+		if m.IDType == idType {
+			return nil
+		}
+	}
+	return fmt.Errorf("the Analytics report ID type must be %v", strings.Join(contentIDTypes[m.Content], " or "))
+}
+
+// Capabilities returns the capabilities needed to get the members, in addition to those the subcommand needs.
+func (m *MemberSource) Capabilities() []api.Capability {
+	if m.Analytics() {
+		return []api.Capability{api.AnalyticsRead}
+	}
+	return []api.Capability{}
+}
+
+// Estimate returns an Estimate function for subcommands which get the members,
+// and then make callsPerMember API calls for each member.
+// Estimating the calls for an Analytics report runs the report to count its rows, and the IDs are kept for Members.
+func (m *MemberSource) Estimate(callsPerMember int) func(context.Context, *api.Client) (int, error) {
+	return func(ctx context.Context, c *api.Client) (calls int, err error) {
+		if !m.Analytics() {
+			return SetEstimate(&m.SetName, &m.SetID, callsPerMember)(ctx, c)
+		}
+		IDs, pages, err := m.analyticsIDs(ctx, c)
+		if err != nil {
+			return calls, err
+		}
+		// Items are looked up by their PIDs or barcodes, bibs need no lookup.
+		if m.IDType != MMSIDType {
+			callsPerMember++
+		}
+		return pages + len(IDs)*callsPerMember, nil
+	}
+}
+
+// String describes where the members came from, for use in messages.
+func (m *MemberSource) String() string {
+	if m.description != "" {
+		return m.description
+	}
+	if m.Analytics() {
+		return fmt.Sprintf("the Analytics report '%v'", m.AnalyticsPath)
+	}
+	if m.SetName != "" {
+		return fmt.Sprintf("'%v'", m.SetName)
+	}
+	return fmt.Sprintf("the set with ID %v", m.SetID)
+}
+
+// Members returns the members from the set or Analytics report.
+// Errors are logged, and an error summarizing them is returned.
+func (m *MemberSource) Members(ctx context.Context, c *api.Client) (members []api.Member, err error) {
+	errs := []error{}
+	if m.Analytics() {
+		members, errs, err = m.analyticsMembers(ctx, c)
+	} else {
+		members, errs, err = m.setMembers(ctx, c)
+	}
+	if err != nil {
+		return members, err
+	}
+	if len(errs) != 0 {
+		for _, err := range errs {
+			log.Println(err)
+		}
+		return members, fmt.Errorf("%v error(s) occured when retrieving the members of %v", len(errs), m)
+	}
+	return members, nil
+}
+
+// setMembers returns the members of the set.
+func (m *MemberSource) setMembers(ctx context.Context, c *api.Client) (members []api.Member, errs []error, err error) {
+	set, err := c.SetFromNameOrID(ctx, m.SetName, m.SetID)
+	if err != nil {
+		return members, errs, err
+	}
+	// Alma calls the content of portfolio sets IEP, for inventory electronic portfolios, but PORTFOLIO has also been seen.
+	if set.Type != "ITEMIZED" || (set.Content != m.Content && !(m.Content == PortfolioContent && set.Content == "PORTFOLIO")) {
+		return members, errs, fmt.Errorf("the set must be an itemized set of %v", contentDescriptions[m.Content])
+	}
+	m.description = fmt.Sprintf("'%v' (ID %v)", set.Name, set.ID)
+	members, errs = c.SetMembers(ctx, set)
+	return members, errs, nil
+}
+
+// analyticsMembers returns members for the IDs in the Analytics report column.
+func (m *MemberSource) analyticsMembers(ctx context.Context, c *api.Client) (members []api.Member, errs []error, err error) {
+	IDs, _, err := m.analyticsIDs(ctx, c)
+	if err != nil {
+		return members, errs, err
+	}
+	log.Printf("%v ID(s) found in %v.\n", len(IDs), m)
+	items := []api.Item{}
+	switch m.IDType {
+	case MMSIDType:
+		for _, ID := range IDs {
+			members = append(members, api.Member{ID: ID, Link: "/almaws/v1/bibs/" + url.PathEscape(ID)})
+		}
+		return members, errs, nil
+	case PIDType:
+		items, errs = c.ItemsFromPIDs(ctx, IDs)
+	case BarcodeType:
+		items, errs = c.ItemsFromBarcodes(ctx, IDs)
+	}
+	for _, item := range items {
+		members = append(members, api.Member{ID: item.PID, Link: item.Link})
+	}
+	return members, errs, nil
+}
+
+// analyticsIDs runs the Analytics report, and returns the unique IDs in the column and the number of pages of results.
+// The report is only run the first time, later calls return the same IDs.
+func (m *MemberSource) analyticsIDs(ctx context.Context, c *api.Client) (IDs []string, pages int, err error) {
+	if m.reportRan {
+		return m.reportIDs, m.pages, nil
+	}
+	seen := map[string]bool{}
+	name := ""
+	err = c.AnalyticsReportPages(ctx, api.AnalyticsQuery{Path: m.AnalyticsPath}, func(columns []api.AnalyticsColumn, rows []api.AnalyticsRow) error {
+		pages++
+		if name == "" {
+			column, err := IDColumn(columns, m.AnalyticsColumn)
+			if err != nil {
+				return err
+			}
+			name = column.Name
+		}
+		for _, row := range rows {
+			ID := strings.TrimSpace(row[name])
+			if ID != "" && !seen[ID] {
+				IDs = append(IDs, ID)
+				seen[ID] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return IDs, pages, err
+	}
+	m.reportRan, m.reportIDs, m.pages = true, IDs, pages
+	return IDs, pages, nil
+}
+
+// IDColumn returns the column with the heading, matched without regard to case.
+// If the heading is empty, the first column which isn't the placeholder column is returned.
+func IDColumn(columns []api.AnalyticsColumn, heading string) (column api.AnalyticsColumn, err error) {
+	headings := []string{}
+	for _, column := range columns {
+		if column.Placeholder() {
+			continue
+		}
+		if heading == "" || strings.EqualFold(strings.TrimSpace(column.Heading), strings.TrimSpace(heading)) {
+			return column, nil
+		}
+		headings = append(headings, "'"+column.Heading+"'")
+	}
+	if heading == "" {
+		return column, fmt.Errorf("the Analytics report has no columns")
+	}
+	return column, fmt.Errorf("the Analytics report has no '%v' column, the columns are %v", heading, strings.Join(headings, ", "))
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+package subcommand
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cu-library/almatoolkit/api"
+)
+
+// TestIDColumn checks that the ID column is found by heading, or defaults to the first real column.
+func TestIDColumn(t *testing.T) {
+	columns := []api.AnalyticsColumn{
+		{Name: "Column0", Heading: "0"},
+		{Name: "Column1", Heading: "Barcode"},
+		{Name: "Column2", Heading: "Item PID"},
+	}
+	tests := []struct {
+		heading string
+		want    string
+	}{
+		{"", "Column1"},
+		{"item pid", "Column2"},
+		{" Barcode ", "Column1"},
+	}
+	for _, test := range tests {
+		column, err := IDColumn(columns, test.heading)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if column.Name != test.want {
+			t.Errorf("IDColumn(%q) = %v, want %v", test.heading, column.Name, test.want)
+		}
+	}
+	_, err := IDColumn(columns, "MMS Id")
+	if err == nil {
+		t.Fatal("expected an error for a missing column")
+	}
+	_, err = IDColumn(columns[:1], "")
+	if err == nil {
+		t.Fatal("expected an error for a report with only the placeholder column")
+	}
+}
+
+// TestMemberSourceValidate checks that exactly one source is accepted, with an ID type which suits the content.
+func TestMemberSourceValidate(t *testing.T) {
+	tests := []struct {
+		content string
+		args    []string
+		valid   bool
+	}{
+		{ItemContent, []string{"-setid", "1"}, true},
+		{ItemContent, []string{"-analytics-path", "/shared/Items"}, true},
+		{ItemContent, []string{"-analytics-path", "/shared/Items", "-analytics-idtype", "pid"}, true},
+		{ItemContent, []string{"-analytics-path", "/shared/Items", "-analytics-idtype", "mms_id"}, false},
+		{ItemContent, []string{"-analytics-path", "/shared/Items", "-setname", "Items"}, false},
+		{ItemContent, []string{}, false},
+		{BibContent, []string{"-analytics-path", "/shared/Bibs"}, true},
+		{BibContent, []string{"-setid", "1", "-setname", "Bibs"}, false},
+		{PortfolioContent, []string{"-setname", "Portfolios"}, true},
+	}
+	for _, test := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		source := NewMemberSource(fs, test.content)
+		err := fs.Parse(test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = source.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%v %q: unexpected validation result %v", test.content, test.args, err)
+		}
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	NewMemberSource(fs, PortfolioContent)
+	if fs.Lookup("analytics-path") != nil {
+		t.Fatal("portfolios can't come from an Analytics report")
+	}
+}
+
+// TestMemberSourceAnalyticsOnce checks that the Analytics report is only run once when the calls are estimated before the members are retrieved.
+func TestMemberSourceAnalyticsOnce(t *testing.T) {
+	runs := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/almaws/v1/analytics/reports" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		runs++
+		fmt.Fprint(w, `<report><QueryResult><IsFinished>true</IsFinished><ResultXml>`+
+			`<rowset xmlns="urn:schemas-microsoft-com:xml-analysis:rowset">`+
+			`<xsd:schema xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:saw-sql="urn:saw-sql"><xsd:complexType name="Row"><xsd:sequence>`+
+			`<xsd:element name="Column0" type="xsd:int" saw-sql:columnHeading="0"/>`+
+			`<xsd:element name="Column1" type="xsd:string" saw-sql:columnHeading="MMS Id"/>`+
+			`</xsd:sequence></xsd:complexType></xsd:schema>`+
+			`<Row><Column0>0</Column0><Column1>991</Column1></Row><Row><Column0>0</Column0><Column1>992</Column1></Row>`+
+			`</rowset></ResultXml></QueryResult></report>`)
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &api.Client{
+		Client: ts.Client(),
+		Host:   tsURL.Host,
+	}
+	m := &MemberSource{AnalyticsPath: "/shared/Reports/Bibs", IDType: MMSIDType, Content: BibContent}
+	calls, err := m.Estimate(1)(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, one page and one per bib, got %v", calls)
+	}
+	members, err := m.Members(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[1].ID != "992" {
+		t.Fatalf("unexpected members %v", members)
+	}
+	if runs != 1 {
+		t.Fatalf("expected the report to run once, it ran %v times", runs)
+	}
+}
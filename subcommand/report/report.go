@@ -0,0 +1,162 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package report writes the rows of subcommand reports as CSV or JSON.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// The report formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// FormatUsage describes the formats, for use in flag help.
+const FormatUsage = "The report format, csv or json. JSON reports are a list of objects keyed by the CSV column names."
+
+// ValidateFormat returns an error if the format is not a report format.
+func ValidateFormat(format string) error {
+	if format != FormatCSV && format != FormatJSON {
+		return fmt.Errorf("'%v' is not a report format, use %v or %v", format, FormatCSV, FormatJSON)
+	}
+	return nil
+}
+
+// Writer writes the rows of a report. Close must be called after the last row is written.
+// WriteValues writes rows of strings, numbers, bools, and nils. CSV reports write them as text,
+// nil as an empty string, and JSON reports keep their types.
+type Writer interface {
+	Write(row []string) error
+	WriteValues(row []interface{}) error
+	Close() error
+}
+
+// NewWriter returns a Writer for the format, which writes the header if the format needs one.
+func NewWriter(w io.Writer, format string, header []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		err := cw.Write(header)
+		if err != nil {
+			return nil, fmt.Errorf("error writing csv header: %w", err)
+		}
+		return &csvWriter{cw}, nil
+	case FormatJSON:
+		_, err := io.WriteString(w, "[")
+		if err != nil {
+			return nil, fmt.Errorf("error writing json: %w", err)
+		}
+		return &jsonWriter{w: w, header: header}, nil
+	}
+	return nil, ValidateFormat(format)
+}
+
+// csvWriter writes rows as CSV lines.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(row []string) error {
+	err := c.w.Write(row)
+	if err != nil {
+		return fmt.Errorf("error writing line to csv: %w", err)
+	}
+	return nil
+}
+
+func (c *csvWriter) WriteValues(row []interface{}) error {
+	line := make([]string, len(row))
+	for i, value := range row {
+		line[i] = FormatValue(value)
+	}
+	return c.Write(line)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	err := c.w.Error()
+	if err != nil {
+		return fmt.Errorf("error after flushing csv: %w", err)
+	}
+	return nil
+}
+
+// jsonWriter writes rows as objects in a JSON list, one per line.
+type jsonWriter struct {
+	w      io.Writer
+	header []string
+	rows   int
+}
+
+func (j *jsonWriter) Write(row []string) error {
+	values := make([]interface{}, len(row))
+	for i, value := range row {
+		values[i] = value
+	}
+	return j.WriteValues(values)
+}
+
+func (j *jsonWriter) WriteValues(row []interface{}) error {
+	if len(row) != len(j.header) {
+		return fmt.Errorf("the row has %v columns, but the header has %v", len(row), len(j.header))
+	}
+	// Build the object by hand to keep the columns in order.
+	line := []byte("\n{")
+	for i, column := range j.header {
+		if i != 0 {
+			line = append(line, ',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(row[i])
+		if err != nil {
+			return err
+		}
+		line = append(line, key...)
+		line = append(line, ':')
+		line = append(line, value...)
+	}
+	line = append(line, '}')
+	if j.rows != 0 {
+		line = append([]byte(","), line...)
+	}
+	_, err := j.w.Write(line)
+	if err != nil {
+		return fmt.Errorf("error writing json: %w", err)
+	}
+	j.rows++
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	if err != nil {
+		return fmt.Errorf("error writing json: %w", err)
+	}
+	return nil
+}
+
+// FormatValue formats a value as text. Nil is formatted as an empty string,
+// and floats are formatted without exponents or trailing zeros.
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Carleton University Library.
+// All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE.txt file.
+
+// Package report writes the rows of subcommand reports as CSV or JSON.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestWriters checks the output of the report writers.
+func TestWriters(t *testing.T) {
+	header := []string{"Code", "Description"}
+	rows := [][]string{{"MAIN", "Main Library"}, {"LAW", "Law, \"Library\""}}
+	expected := map[string]string{
+		FormatCSV: "Code,Description\nMAIN,Main Library\nLAW,\"Law, \"\"Library\"\"\"\n",
+		FormatJSON: "[\n{\"Code\":\"MAIN\",\"Description\":\"Main Library\"},\n" +
+			"{\"Code\":\"LAW\",\"Description\":\"Law, \\\"Library\\\"\"}\n]\n",
+	}
+	for format, want := range expected {
+		var b bytes.Buffer
+		w, err := NewWriter(&b, format, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			err := w.Write(row)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want {
+			t.Fatalf("unexpected %v output:\n%v", format, b.String())
+		}
+	}
+}
+
+// TestJSONEmpty checks that a JSON report with no rows is an empty list.
+func TestJSONEmpty(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, FormatJSON, []string{"Code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]string
+	err = json.Unmarshal(b.Bytes(), &rows)
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("unexpected output %q, %v", b.String(), err)
+	}
+	if ValidateFormat("xml") == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+// TestWriteValues checks that CSV reports format values as text, and JSON reports keep their types.
+func TestWriteValues(t *testing.T) {
+	header := []string{"Barcode", "Loans", "Rate", "Lost", "Note"}
+	row := []interface{}{"39000000001", int64(4), 0.25, false, nil}
+	expected := map[string]string{
+		FormatCSV:  "Barcode,Loans,Rate,Lost,Note\n39000000001,4,0.25,false,\n",
+		FormatJSON: "[\n{\"Barcode\":\"39000000001\",\"Loans\":4,\"Rate\":0.25,\"Lost\":false,\"Note\":null}\n]\n",
+	}
+	for format, want := range expected {
+		var b bytes.Buffer
+		w, err := NewWriter(&b, format, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.WriteValues(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want {
+			t.Fatalf("unexpected %v output:\n%v", format, b.String())
+		}
+	}
+}
@@ -17,11 +17,23 @@ import (
 
 // Config stores information about subcommands.
 type Config struct {
-	ReadAccess    []string                                 // The API endpoints which will require read-only access.
-	WriteAccess   []string                                 // The API endpoints which will require write access.
-	FlagSet       *flag.FlagSet                            // The Flag set for this subcommand.
-	ValidateFlags func() error                             // A function which validates that the flagset is valid after it is parsed.
-	Run           func(context.Context, *api.Client) error // Call this function for this subcommand.
+	Capabilities      []api.Capability                                // The permissions the API key needs for this subcommand.
+	FlagSet           *flag.FlagSet                                   // The Flag set for this subcommand.
+	ValidateFlags     func() error                                    // A function which validates that the flagset is valid after it is parsed.
+	ExtraCapabilities func() []api.Capability                         // An optional function which returns the permissions the flags need, called after ValidateFlags.
+	CodeChecks        []CodeCheck                                     // Flags which must hold codes configured in Alma, checked against the API before Run.
+	Estimate          func(context.Context, *api.Client) (int, error) // An optional function which estimates the number of API calls Run will make.
+	Run               func(context.Context, *api.Client) error        // Call this function for this subcommand.
+}
+
+// Writes returns true if the subcommand needs any read/write capabilities, meaning it makes changes in Alma.
+func (c *Config) Writes() bool {
+	for _, capability := range c.Capabilities {
+		if capability.Write {
+			return true
+		}
+	}
+	return false
 }
 
 // Registry maps the string from the command line to the properties of a subcommand.
@@ -44,6 +56,23 @@ func ValidateSetNameAndSetIDFlags(name, ID string) error {
 	return nil
 }
 
+// SetEstimate returns an Estimate function for subcommands which look up a set, get its members,
+// and then make callsPerMember API calls for each member.
+func SetEstimate(name, ID *string, callsPerMember int) func(context.Context, *api.Client) (int, error) {
+	return func(ctx context.Context, c *api.Client) (calls int, err error) {
+		set, err := c.SetFromNameOrID(ctx, *name, *ID)
+		if err != nil {
+			return calls, err
+		}
+		// Looking up the set by name takes an extra call.
+		calls = 1
+		if *name != "" {
+			calls = 2
+		}
+		return calls + set.Pages() + set.NumberOfMembers*callsPerMember, nil
+	}
+}
+
 // Usage prints the name, description, flags, and env vars for a subcommand.
 func Usage(fs *flag.FlagSet, envPrefix string, description string) {
 	usageNameAndDescription(fs, description)